@@ -139,6 +139,58 @@ func (Piece_Priority) EnumDescriptor() ([]byte, []int) {
 	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{2, 0}
 }
 
+type File_Priority int32
+
+const (
+	File_DEFAULT File_Priority = 0
+	File_SKIP    File_Priority = 1
+	File_NORMAL  File_Priority = 2
+	File_HIGH    File_Priority = 3
+)
+
+// Enum value maps for File_Priority.
+var (
+	File_Priority_name = map[int32]string{
+		0: "DEFAULT",
+		1: "SKIP",
+		2: "NORMAL",
+		3: "HIGH",
+	}
+	File_Priority_value = map[string]int32{
+		"DEFAULT": 0,
+		"SKIP":    1,
+		"NORMAL":  2,
+		"HIGH":    3,
+	}
+)
+
+func (x File_Priority) Enum() *File_Priority {
+	p := new(File_Priority)
+	*p = x
+	return p
+}
+
+func (x File_Priority) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (File_Priority) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_torrent_web_seeder_proto_enumTypes[2].Descriptor()
+}
+
+func (File_Priority) Type() protoreflect.EnumType {
+	return &file_proto_torrent_web_seeder_proto_enumTypes[2]
+}
+
+func (x File_Priority) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use File_Priority.Descriptor instead.
+func (File_Priority) EnumDescriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{4, 0}
+}
+
 // Stat request message
 type StatRequest struct {
 	state         protoimpl.MessageState
@@ -191,13 +243,15 @@ type StatReply struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Total     int64            `protobuf:"varint,1,opt,name=total,proto3" json:"total"`
-	Completed int64            `protobuf:"varint,2,opt,name=completed,proto3" json:"completed"`
-	Peers     int32            `protobuf:"varint,3,opt,name=peers,proto3" json:"peers"`
-	Status    StatReply_Status `protobuf:"varint,4,opt,name=status,proto3,enum=StatReply_Status" json:"status"`
-	Pieces    []*Piece         `protobuf:"bytes,5,rep,name=pieces,proto3" json:"pieces"`
-	Seeders   int32            `protobuf:"varint,6,opt,name=seeders,proto3" json:"seeders"`
-	Leechers  int32            `protobuf:"varint,7,opt,name=leechers,proto3" json:"leechers"`
+	Total        int64            `protobuf:"varint,1,opt,name=total,proto3" json:"total"`
+	Completed    int64            `protobuf:"varint,2,opt,name=completed,proto3" json:"completed"`
+	Peers        int32            `protobuf:"varint,3,opt,name=peers,proto3" json:"peers"`
+	Status       StatReply_Status `protobuf:"varint,4,opt,name=status,proto3,enum=StatReply_Status" json:"status"`
+	Pieces       []*Piece         `protobuf:"bytes,5,rep,name=pieces,proto3" json:"pieces"`
+	Seeders      int32            `protobuf:"varint,6,opt,name=seeders,proto3" json:"seeders"`
+	Leechers     int32            `protobuf:"varint,7,opt,name=leechers,proto3" json:"leechers"`
+	WebseedBytes int64            `protobuf:"varint,8,opt,name=webseed_bytes,json=webseedBytes,proto3" json:"webseed_bytes"`
+	Priority     File_Priority    `protobuf:"varint,9,opt,name=priority,proto3,enum=File_Priority" json:"priority"`
 }
 
 func (x *StatReply) Reset() {
@@ -279,6 +333,20 @@ func (x *StatReply) GetLeechers() int32 {
 	return 0
 }
 
+func (x *StatReply) GetWebseedBytes() int64 {
+	if x != nil {
+		return x.WebseedBytes
+	}
+	return 0
+}
+
+func (x *StatReply) GetPriority() File_Priority {
+	if x != nil {
+		return x.Priority
+	}
+	return File_DEFAULT
+}
+
 type Piece struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -382,7 +450,8 @@ type File struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path"`
+	Path     string        `protobuf:"bytes,1,opt,name=path,proto3" json:"path"`
+	Priority File_Priority `protobuf:"varint,2,opt,name=priority,proto3,enum=File_Priority" json:"priority"`
 }
 
 func (x *File) Reset() {
@@ -422,6 +491,13 @@ func (x *File) GetPath() string {
 	return ""
 }
 
+func (x *File) GetPriority() File_Priority {
+	if x != nil {
+		return x.Priority
+	}
+	return File_DEFAULT
+}
+
 // Files reply message
 type FilesReply struct {
 	state         protoimpl.MessageState
@@ -468,6 +544,1135 @@ func (x *FilesReply) GetFiles() []*File {
 	return nil
 }
 
+// Add magnet request message
+type AddMagnetRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Magnet string `protobuf:"bytes,1,opt,name=magnet,proto3" json:"magnet"`
+}
+
+func (x *AddMagnetRequest) Reset() {
+	*x = AddMagnetRequest{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddMagnetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddMagnetRequest) ProtoMessage() {}
+
+func (x *AddMagnetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddMagnetRequest.ProtoReflect.Descriptor instead.
+func (*AddMagnetRequest) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AddMagnetRequest) GetMagnet() string {
+	if x != nil {
+		return x.Magnet
+	}
+	return ""
+}
+
+// Add magnet reply message
+type AddMagnetReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	InfoHash string  `protobuf:"bytes,1,opt,name=info_hash,json=infoHash,proto3" json:"info_hash"`
+	Name     string  `protobuf:"bytes,2,opt,name=name,proto3" json:"name"`
+	Files    []*File `protobuf:"bytes,3,rep,name=files,proto3" json:"files"`
+}
+
+func (x *AddMagnetReply) Reset() {
+	*x = AddMagnetReply{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddMagnetReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddMagnetReply) ProtoMessage() {}
+
+func (x *AddMagnetReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddMagnetReply.ProtoReflect.Descriptor instead.
+func (*AddMagnetReply) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AddMagnetReply) GetInfoHash() string {
+	if x != nil {
+		return x.InfoHash
+	}
+	return ""
+}
+
+func (x *AddMagnetReply) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AddMagnetReply) GetFiles() []*File {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+// List torrents request message
+type ListTorrentsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListTorrentsRequest) Reset() {
+	*x = ListTorrentsRequest{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTorrentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTorrentsRequest) ProtoMessage() {}
+
+func (x *ListTorrentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTorrentsRequest.ProtoReflect.Descriptor instead.
+func (*ListTorrentsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{8}
+}
+
+type ActiveTorrent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	InfoHash    string `protobuf:"bytes,1,opt,name=info_hash,json=infoHash,proto3" json:"info_hash"`
+	Name        string `protobuf:"bytes,2,opt,name=name,proto3" json:"name"`
+	AddedAt     int64  `protobuf:"varint,3,opt,name=added_at,json=addedAt,proto3" json:"added_at"`
+	LastTouch   int64  `protobuf:"varint,4,opt,name=last_touch,json=lastTouch,proto3" json:"last_touch"`
+	BytesCached int64  `protobuf:"varint,5,opt,name=bytes_cached,json=bytesCached,proto3" json:"bytes_cached"`
+	Peers       int32  `protobuf:"varint,6,opt,name=peers,proto3" json:"peers"`
+	Pinned      bool   `protobuf:"varint,7,opt,name=pinned,proto3" json:"pinned"`
+	Ttl         int64  `protobuf:"varint,8,opt,name=ttl,proto3" json:"ttl"`
+	Readers     int32  `protobuf:"varint,9,opt,name=readers,proto3" json:"readers"`
+}
+
+func (x *ActiveTorrent) Reset() {
+	*x = ActiveTorrent{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ActiveTorrent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ActiveTorrent) ProtoMessage() {}
+
+func (x *ActiveTorrent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ActiveTorrent.ProtoReflect.Descriptor instead.
+func (*ActiveTorrent) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ActiveTorrent) GetInfoHash() string {
+	if x != nil {
+		return x.InfoHash
+	}
+	return ""
+}
+
+func (x *ActiveTorrent) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ActiveTorrent) GetAddedAt() int64 {
+	if x != nil {
+		return x.AddedAt
+	}
+	return 0
+}
+
+func (x *ActiveTorrent) GetLastTouch() int64 {
+	if x != nil {
+		return x.LastTouch
+	}
+	return 0
+}
+
+func (x *ActiveTorrent) GetBytesCached() int64 {
+	if x != nil {
+		return x.BytesCached
+	}
+	return 0
+}
+
+func (x *ActiveTorrent) GetPeers() int32 {
+	if x != nil {
+		return x.Peers
+	}
+	return 0
+}
+
+func (x *ActiveTorrent) GetPinned() bool {
+	if x != nil {
+		return x.Pinned
+	}
+	return false
+}
+
+func (x *ActiveTorrent) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *ActiveTorrent) GetReaders() int32 {
+	if x != nil {
+		return x.Readers
+	}
+	return 0
+}
+
+// List torrents reply message
+type ListTorrentsReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Torrents []*ActiveTorrent `protobuf:"bytes,1,rep,name=torrents,proto3" json:"torrents"`
+}
+
+func (x *ListTorrentsReply) Reset() {
+	*x = ListTorrentsReply{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTorrentsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTorrentsReply) ProtoMessage() {}
+
+func (x *ListTorrentsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTorrentsReply.ProtoReflect.Descriptor instead.
+func (*ListTorrentsReply) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListTorrentsReply) GetTorrents() []*ActiveTorrent {
+	if x != nil {
+		return x.Torrents
+	}
+	return nil
+}
+
+// Drop torrent request message
+type DropTorrentRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	InfoHash string `protobuf:"bytes,1,opt,name=info_hash,json=infoHash,proto3" json:"info_hash"`
+}
+
+func (x *DropTorrentRequest) Reset() {
+	*x = DropTorrentRequest{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropTorrentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropTorrentRequest) ProtoMessage() {}
+
+func (x *DropTorrentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropTorrentRequest.ProtoReflect.Descriptor instead.
+func (*DropTorrentRequest) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DropTorrentRequest) GetInfoHash() string {
+	if x != nil {
+		return x.InfoHash
+	}
+	return ""
+}
+
+// Drop torrent reply message
+type DropTorrentReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *DropTorrentReply) Reset() {
+	*x = DropTorrentReply{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropTorrentReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropTorrentReply) ProtoMessage() {}
+
+func (x *DropTorrentReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropTorrentReply.ProtoReflect.Descriptor instead.
+func (*DropTorrentReply) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{12}
+}
+
+// Pin torrent request message
+type PinTorrentRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	InfoHash string `protobuf:"bytes,1,opt,name=info_hash,json=infoHash,proto3" json:"info_hash"`
+	Pinned   bool   `protobuf:"varint,2,opt,name=pinned,proto3" json:"pinned"`
+}
+
+func (x *PinTorrentRequest) Reset() {
+	*x = PinTorrentRequest{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PinTorrentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PinTorrentRequest) ProtoMessage() {}
+
+func (x *PinTorrentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PinTorrentRequest.ProtoReflect.Descriptor instead.
+func (*PinTorrentRequest) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PinTorrentRequest) GetInfoHash() string {
+	if x != nil {
+		return x.InfoHash
+	}
+	return ""
+}
+
+func (x *PinTorrentRequest) GetPinned() bool {
+	if x != nil {
+		return x.Pinned
+	}
+	return false
+}
+
+// Pin torrent reply message
+type PinTorrentReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *PinTorrentReply) Reset() {
+	*x = PinTorrentReply{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PinTorrentReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PinTorrentReply) ProtoMessage() {}
+
+func (x *PinTorrentReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PinTorrentReply.ProtoReflect.Descriptor instead.
+func (*PinTorrentReply) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{14}
+}
+
+// Set file priority request message
+type SetFilePriorityRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path     string        `protobuf:"bytes,1,opt,name=path,proto3" json:"path"`
+	Priority File_Priority `protobuf:"varint,2,opt,name=priority,proto3,enum=File_Priority" json:"priority"`
+}
+
+func (x *SetFilePriorityRequest) Reset() {
+	*x = SetFilePriorityRequest{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetFilePriorityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetFilePriorityRequest) ProtoMessage() {}
+
+func (x *SetFilePriorityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetFilePriorityRequest.ProtoReflect.Descriptor instead.
+func (*SetFilePriorityRequest) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SetFilePriorityRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SetFilePriorityRequest) GetPriority() File_Priority {
+	if x != nil {
+		return x.Priority
+	}
+	return File_DEFAULT
+}
+
+// Set file priority reply message
+type SetFilePriorityReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SetFilePriorityReply) Reset() {
+	*x = SetFilePriorityReply{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetFilePriorityReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetFilePriorityReply) ProtoMessage() {}
+
+func (x *SetFilePriorityReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetFilePriorityReply.ProtoReflect.Descriptor instead.
+func (*SetFilePriorityReply) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{16}
+}
+
+// Prefetch request message. Explicit byte range [start, end) is used when
+// end is set, otherwise head and tail of size bytes (0 = server default).
+type PrefetchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Path  string `protobuf:"bytes,1,opt,name=path,proto3" json:"path"`
+	Size  int64  `protobuf:"varint,2,opt,name=size,proto3" json:"size"`
+	Start int64  `protobuf:"varint,3,opt,name=start,proto3" json:"start"`
+	End   int64  `protobuf:"varint,4,opt,name=end,proto3" json:"end"`
+}
+
+func (x *PrefetchRequest) Reset() {
+	*x = PrefetchRequest{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrefetchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrefetchRequest) ProtoMessage() {}
+
+func (x *PrefetchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrefetchRequest.ProtoReflect.Descriptor instead.
+func (*PrefetchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PrefetchRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *PrefetchRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *PrefetchRequest) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *PrefetchRequest) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+// Prefetch reply message
+type PrefetchReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pieces     int32 `protobuf:"varint,1,opt,name=pieces,proto3" json:"pieces"`
+	Complete   int32 `protobuf:"varint,2,opt,name=complete,proto3" json:"complete"`
+	KeepAlive  int64 `protobuf:"varint,3,opt,name=keep_alive,json=keepAlive,proto3" json:"keep_alive"`
+	FirstPiece int32 `protobuf:"varint,4,opt,name=first_piece,json=firstPiece,proto3" json:"first_piece"`
+	LastPiece  int32 `protobuf:"varint,5,opt,name=last_piece,json=lastPiece,proto3" json:"last_piece"`
+}
+
+func (x *PrefetchReply) Reset() {
+	*x = PrefetchReply{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrefetchReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrefetchReply) ProtoMessage() {}
+
+func (x *PrefetchReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrefetchReply.ProtoReflect.Descriptor instead.
+func (*PrefetchReply) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PrefetchReply) GetPieces() int32 {
+	if x != nil {
+		return x.Pieces
+	}
+	return 0
+}
+
+func (x *PrefetchReply) GetComplete() int32 {
+	if x != nil {
+		return x.Complete
+	}
+	return 0
+}
+
+func (x *PrefetchReply) GetKeepAlive() int64 {
+	if x != nil {
+		return x.KeepAlive
+	}
+	return 0
+}
+
+func (x *PrefetchReply) GetFirstPiece() int32 {
+	if x != nil {
+		return x.FirstPiece
+	}
+	return 0
+}
+
+func (x *PrefetchReply) GetLastPiece() int32 {
+	if x != nil {
+		return x.LastPiece
+	}
+	return 0
+}
+
+type Job struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id             string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+	InfoHash       string `protobuf:"bytes,2,opt,name=info_hash,json=infoHash,proto3" json:"info_hash"`
+	Path           string `protobuf:"bytes,3,opt,name=path,proto3" json:"path"`
+	Priority       int32  `protobuf:"varint,4,opt,name=priority,proto3" json:"priority"`
+	State          string `protobuf:"bytes,5,opt,name=state,proto3" json:"state"`
+	Error          string `protobuf:"bytes,6,opt,name=error,proto3" json:"error"`
+	Files          int32  `protobuf:"varint,7,opt,name=files,proto3" json:"files"`
+	CompletedFiles int32  `protobuf:"varint,8,opt,name=completed_files,json=completedFiles,proto3" json:"completed_files"`
+	Bytes          int64  `protobuf:"varint,9,opt,name=bytes,proto3" json:"bytes"`
+	CompletedBytes int64  `protobuf:"varint,10,opt,name=completed_bytes,json=completedBytes,proto3" json:"completed_bytes"`
+	CreatedAt      int64  `protobuf:"varint,11,opt,name=created_at,json=createdAt,proto3" json:"created_at"`
+	StartedAt      int64  `protobuf:"varint,12,opt,name=started_at,json=startedAt,proto3" json:"started_at"`
+	FinishedAt     int64  `protobuf:"varint,13,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at"`
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Job) GetInfoHash() string {
+	if x != nil {
+		return x.InfoHash
+	}
+	return ""
+}
+
+func (x *Job) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Job) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *Job) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetFiles() int32 {
+	if x != nil {
+		return x.Files
+	}
+	return 0
+}
+
+func (x *Job) GetCompletedFiles() int32 {
+	if x != nil {
+		return x.CompletedFiles
+	}
+	return 0
+}
+
+func (x *Job) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *Job) GetCompletedBytes() int64 {
+	if x != nil {
+		return x.CompletedBytes
+	}
+	return 0
+}
+
+func (x *Job) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Job) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *Job) GetFinishedAt() int64 {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return 0
+}
+
+// Submit job request message. Empty path downloads whole torrent.
+type SubmitJobRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	InfoHash string `protobuf:"bytes,1,opt,name=info_hash,json=infoHash,proto3" json:"info_hash"`
+	Path     string `protobuf:"bytes,2,opt,name=path,proto3" json:"path"`
+	Priority int32  `protobuf:"varint,3,opt,name=priority,proto3" json:"priority"`
+}
+
+func (x *SubmitJobRequest) Reset() {
+	*x = SubmitJobRequest{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitJobRequest) ProtoMessage() {}
+
+func (x *SubmitJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitJobRequest.ProtoReflect.Descriptor instead.
+func (*SubmitJobRequest) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SubmitJobRequest) GetInfoHash() string {
+	if x != nil {
+		return x.InfoHash
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+// Submit job reply message
+type SubmitJobReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Job *Job `protobuf:"bytes,1,opt,name=job,proto3" json:"job"`
+}
+
+func (x *SubmitJobReply) Reset() {
+	*x = SubmitJobReply{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitJobReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitJobReply) ProtoMessage() {}
+
+func (x *SubmitJobReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitJobReply.ProtoReflect.Descriptor instead.
+func (*SubmitJobReply) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *SubmitJobReply) GetJob() *Job {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+// Cancel job request message
+type CancelJobRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id"`
+}
+
+func (x *CancelJobRequest) Reset() {
+	*x = CancelJobRequest{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobRequest) ProtoMessage() {}
+
+func (x *CancelJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobRequest.ProtoReflect.Descriptor instead.
+func (*CancelJobRequest) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *CancelJobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// Cancel job reply message
+type CancelJobReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *CancelJobReply) Reset() {
+	*x = CancelJobReply{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobReply) ProtoMessage() {}
+
+func (x *CancelJobReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobReply.ProtoReflect.Descriptor instead.
+func (*CancelJobReply) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{23}
+}
+
+// List jobs request message
+type ListJobsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListJobsRequest) Reset() {
+	*x = ListJobsRequest{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsRequest) ProtoMessage() {}
+
+func (x *ListJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{24}
+}
+
+// List jobs reply message
+type ListJobsReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Jobs []*Job `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs"`
+}
+
+func (x *ListJobsReply) Reset() {
+	*x = ListJobsReply{}
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsReply) ProtoMessage() {}
+
+func (x *ListJobsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_torrent_web_seeder_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsReply.ProtoReflect.Descriptor instead.
+func (*ListJobsReply) Descriptor() ([]byte, []int) {
+	return file_proto_torrent_web_seeder_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListJobsReply) GetJobs() []*Job {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
 var File_proto_torrent_web_seeder_proto protoreflect.FileDescriptor
 
 var file_proto_torrent_web_seeder_proto_rawDesc = []byte{
@@ -475,7 +1680,7 @@ var file_proto_torrent_web_seeder_proto_rawDesc = []byte{
 	0x77, 0x65, 0x62, 0x2d, 0x73, 0x65, 0x65, 0x64, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
 	0x22, 0x21, 0x0a, 0x0b, 0x53, 0x74, 0x61, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
 	0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70,
-	0x61, 0x74, 0x68, 0x22, 0xd0, 0x02, 0x0a, 0x09, 0x53, 0x74, 0x61, 0x74, 0x52, 0x65, 0x70, 0x6c,
+	0x61, 0x74, 0x68, 0x22, 0xa1, 0x03, 0x0a, 0x09, 0x53, 0x74, 0x61, 0x74, 0x52, 0x65, 0x70, 0x6c,
 	0x79, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
 	0x52, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6d, 0x70, 0x6c,
 	0x65, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x6f, 0x6d, 0x70,
@@ -488,42 +1693,186 @@ var file_proto_torrent_web_seeder_proto_rawDesc = []byte{
 	0x70, 0x69, 0x65, 0x63, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x65, 0x64, 0x65, 0x72,
 	0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x73, 0x65, 0x65, 0x64, 0x65, 0x72, 0x73,
 	0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x65, 0x65, 0x63, 0x68, 0x65, 0x72, 0x73, 0x18, 0x07, 0x20, 0x01,
-	0x28, 0x05, 0x52, 0x08, 0x6c, 0x65, 0x65, 0x63, 0x68, 0x65, 0x72, 0x73, 0x22, 0x78, 0x0a, 0x06,
-	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x0e, 0x49, 0x4e, 0x49, 0x54, 0x49, 0x41,
-	0x4c, 0x49, 0x5a, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x45,
-	0x45, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x49, 0x44, 0x4c, 0x45, 0x10,
-	0x02, 0x12, 0x0e, 0x0a, 0x0a, 0x54, 0x45, 0x52, 0x4d, 0x49, 0x4e, 0x41, 0x54, 0x45, 0x44, 0x10,
-	0x03, 0x12, 0x15, 0x0a, 0x11, 0x57, 0x41, 0x49, 0x54, 0x49, 0x4e, 0x47, 0x5f, 0x46, 0x4f, 0x52,
-	0x5f, 0x50, 0x45, 0x45, 0x52, 0x53, 0x10, 0x04, 0x12, 0x0d, 0x0a, 0x09, 0x52, 0x45, 0x53, 0x54,
-	0x4f, 0x52, 0x49, 0x4e, 0x47, 0x10, 0x05, 0x12, 0x0d, 0x0a, 0x09, 0x42, 0x41, 0x43, 0x4b, 0x49,
-	0x4e, 0x47, 0x55, 0x50, 0x10, 0x06, 0x22, 0xba, 0x01, 0x0a, 0x05, 0x50, 0x69, 0x65, 0x63, 0x65,
-	0x12, 0x1a, 0x0a, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x03, 0x52, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08,
-	0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08,
-	0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x2b, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f,
-	0x72, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0f, 0x2e, 0x50, 0x69, 0x65,
-	0x63, 0x65, 0x2e, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x52, 0x08, 0x70, 0x72, 0x69,
-	0x6f, 0x72, 0x69, 0x74, 0x79, 0x22, 0x4c, 0x0a, 0x08, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74,
-	0x79, 0x12, 0x08, 0x0a, 0x04, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x4e,
-	0x4f, 0x52, 0x4d, 0x41, 0x4c, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x48, 0x49, 0x47, 0x48, 0x10,
-	0x02, 0x12, 0x0d, 0x0a, 0x09, 0x52, 0x45, 0x41, 0x44, 0x41, 0x48, 0x45, 0x41, 0x44, 0x10, 0x03,
-	0x12, 0x08, 0x0a, 0x04, 0x4e, 0x45, 0x58, 0x54, 0x10, 0x04, 0x12, 0x07, 0x0a, 0x03, 0x4e, 0x4f,
-	0x57, 0x10, 0x05, 0x22, 0x0e, 0x0a, 0x0c, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x22, 0x1a, 0x0a, 0x04, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x70,
-	0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x22,
-	0x29, 0x0a, 0x0a, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x1b, 0x0a,
-	0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x05, 0x2e, 0x46,
-	0x69, 0x6c, 0x65, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x32, 0x89, 0x01, 0x0a, 0x10, 0x54,
-	0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x57, 0x65, 0x62, 0x53, 0x65, 0x65, 0x64, 0x65, 0x72, 0x12,
-	0x22, 0x0a, 0x04, 0x53, 0x74, 0x61, 0x74, 0x12, 0x0c, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0a, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x52, 0x65, 0x70, 0x6c,
-	0x79, 0x22, 0x00, 0x12, 0x2a, 0x0a, 0x0a, 0x53, 0x74, 0x61, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61,
-	0x6d, 0x12, 0x0c, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
-	0x0a, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x30, 0x01, 0x12,
-	0x25, 0x0a, 0x05, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x0d, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x73,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52,
-	0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x42, 0x04, 0x5a, 0x02, 0x2e, 0x2f, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	0x28, 0x05, 0x52, 0x08, 0x6c, 0x65, 0x65, 0x63, 0x68, 0x65, 0x72, 0x73, 0x12, 0x23, 0x0a, 0x0d,
+	0x77, 0x65, 0x62, 0x73, 0x65, 0x65, 0x64, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0c, 0x77, 0x65, 0x62, 0x73, 0x65, 0x65, 0x64, 0x42, 0x79, 0x74, 0x65,
+	0x73, 0x12, 0x2a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x0e, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x2e, 0x50, 0x72, 0x69, 0x6f, 0x72,
+	0x69, 0x74, 0x79, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x22, 0x78, 0x0a,
+	0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x0e, 0x49, 0x4e, 0x49, 0x54, 0x49,
+	0x41, 0x4c, 0x49, 0x5a, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x53,
+	0x45, 0x45, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x49, 0x44, 0x4c, 0x45,
+	0x10, 0x02, 0x12, 0x0e, 0x0a, 0x0a, 0x54, 0x45, 0x52, 0x4d, 0x49, 0x4e, 0x41, 0x54, 0x45, 0x44,
+	0x10, 0x03, 0x12, 0x15, 0x0a, 0x11, 0x57, 0x41, 0x49, 0x54, 0x49, 0x4e, 0x47, 0x5f, 0x46, 0x4f,
+	0x52, 0x5f, 0x50, 0x45, 0x45, 0x52, 0x53, 0x10, 0x04, 0x12, 0x0d, 0x0a, 0x09, 0x52, 0x45, 0x53,
+	0x54, 0x4f, 0x52, 0x49, 0x4e, 0x47, 0x10, 0x05, 0x12, 0x0d, 0x0a, 0x09, 0x42, 0x41, 0x43, 0x4b,
+	0x49, 0x4e, 0x47, 0x55, 0x50, 0x10, 0x06, 0x22, 0xba, 0x01, 0x0a, 0x05, 0x50, 0x69, 0x65, 0x63,
+	0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a,
+	0x08, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x08, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x2b, 0x0a, 0x08, 0x70, 0x72, 0x69,
+	0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0f, 0x2e, 0x50, 0x69,
+	0x65, 0x63, 0x65, 0x2e, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x52, 0x08, 0x70, 0x72,
+	0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x22, 0x4c, 0x0a, 0x08, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69,
+	0x74, 0x79, 0x12, 0x08, 0x0a, 0x04, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06,
+	0x4e, 0x4f, 0x52, 0x4d, 0x41, 0x4c, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x48, 0x49, 0x47, 0x48,
+	0x10, 0x02, 0x12, 0x0d, 0x0a, 0x09, 0x52, 0x45, 0x41, 0x44, 0x41, 0x48, 0x45, 0x41, 0x44, 0x10,
+	0x03, 0x12, 0x08, 0x0a, 0x04, 0x4e, 0x45, 0x58, 0x54, 0x10, 0x04, 0x12, 0x07, 0x0a, 0x03, 0x4e,
+	0x4f, 0x57, 0x10, 0x05, 0x22, 0x0e, 0x0a, 0x0c, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x22, 0x7f, 0x0a, 0x04, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04,
+	0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68,
+	0x12, 0x2a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x0e, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x2e, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69,
+	0x74, 0x79, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x22, 0x37, 0x0a, 0x08,
+	0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x0b, 0x0a, 0x07, 0x44, 0x45, 0x46, 0x41,
+	0x55, 0x4c, 0x54, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x53, 0x4b, 0x49, 0x50, 0x10, 0x01, 0x12,
+	0x0a, 0x0a, 0x06, 0x4e, 0x4f, 0x52, 0x4d, 0x41, 0x4c, 0x10, 0x02, 0x12, 0x08, 0x0a, 0x04, 0x48,
+	0x49, 0x47, 0x48, 0x10, 0x03, 0x22, 0x29, 0x0a, 0x0a, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52, 0x65,
+	0x70, 0x6c, 0x79, 0x12, 0x1b, 0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x05, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73,
+	0x22, 0x2a, 0x0a, 0x10, 0x41, 0x64, 0x64, 0x4d, 0x61, 0x67, 0x6e, 0x65, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x61, 0x67, 0x6e, 0x65, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d, 0x61, 0x67, 0x6e, 0x65, 0x74, 0x22, 0x5e, 0x0a, 0x0e,
+	0x41, 0x64, 0x64, 0x4d, 0x61, 0x67, 0x6e, 0x65, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x1b,
+	0x0a, 0x09, 0x69, 0x6e, 0x66, 0x6f, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x69, 0x6e, 0x66, 0x6f, 0x48, 0x61, 0x73, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x1b, 0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x05,
+	0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x22, 0x15, 0x0a, 0x13,
+	0x4c, 0x69, 0x73, 0x74, 0x54, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x22, 0xf7, 0x01, 0x0a, 0x0d, 0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x54, 0x6f,
+	0x72, 0x72, 0x65, 0x6e, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x69, 0x6e, 0x66, 0x6f, 0x5f, 0x68, 0x61,
+	0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x6e, 0x66, 0x6f, 0x48, 0x61,
+	0x73, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x19, 0x0a, 0x08, 0x61, 0x64, 0x64, 0x65, 0x64, 0x5f,
+	0x61, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x61, 0x64, 0x64, 0x65, 0x64, 0x41,
+	0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x74, 0x6f, 0x75, 0x63, 0x68, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x6c, 0x61, 0x73, 0x74, 0x54, 0x6f, 0x75, 0x63, 0x68,
+	0x12, 0x21, 0x0a, 0x0c, 0x62, 0x79, 0x74, 0x65, 0x73, 0x5f, 0x63, 0x61, 0x63, 0x68, 0x65, 0x64,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x62, 0x79, 0x74, 0x65, 0x73, 0x43, 0x61, 0x63,
+	0x68, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x65, 0x65, 0x72, 0x73, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x05, 0x70, 0x65, 0x65, 0x72, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x69, 0x6e,
+	0x6e, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x70, 0x69, 0x6e, 0x6e, 0x65,
+	0x64, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x74, 0x6c, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03,
+	0x74, 0x74, 0x6c, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x18, 0x09,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x72, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x22, 0x3f, 0x0a,
+	0x11, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x12, 0x2a, 0x0a, 0x08, 0x74, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x41, 0x63, 0x74, 0x69, 0x76, 0x65, 0x54, 0x6f, 0x72,
+	0x72, 0x65, 0x6e, 0x74, 0x52, 0x08, 0x74, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x73, 0x22, 0x31,
+	0x0a, 0x12, 0x44, 0x72, 0x6f, 0x70, 0x54, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x69, 0x6e, 0x66, 0x6f, 0x5f, 0x68, 0x61, 0x73,
+	0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x6e, 0x66, 0x6f, 0x48, 0x61, 0x73,
+	0x68, 0x22, 0x12, 0x0a, 0x10, 0x44, 0x72, 0x6f, 0x70, 0x54, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x48, 0x0a, 0x11, 0x50, 0x69, 0x6e, 0x54, 0x6f, 0x72, 0x72,
+	0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x69, 0x6e,
+	0x66, 0x6f, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69,
+	0x6e, 0x66, 0x6f, 0x48, 0x61, 0x73, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x69, 0x6e, 0x6e, 0x65,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x70, 0x69, 0x6e, 0x6e, 0x65, 0x64, 0x22,
+	0x11, 0x0a, 0x0f, 0x50, 0x69, 0x6e, 0x54, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x22, 0x58, 0x0a, 0x16, 0x53, 0x65, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x50, 0x72, 0x69,
+	0x6f, 0x72, 0x69, 0x74, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04,
+	0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68,
+	0x12, 0x2a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x0e, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x2e, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69,
+	0x74, 0x79, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x22, 0x16, 0x0a, 0x14,
+	0x53, 0x65, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x52,
+	0x65, 0x70, 0x6c, 0x79, 0x22, 0x61, 0x0a, 0x0f, 0x50, 0x72, 0x65, 0x66, 0x65, 0x74, 0x63, 0x68,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x73,
+	0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12,
+	0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05,
+	0x73, 0x74, 0x61, 0x72, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e, 0x64, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x22, 0xa2, 0x01, 0x0a, 0x0d, 0x50, 0x72, 0x65, 0x66,
+	0x65, 0x74, 0x63, 0x68, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x69, 0x65,
+	0x63, 0x65, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x70, 0x69, 0x65, 0x63, 0x65,
+	0x73, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x08, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x1d, 0x0a,
+	0x0a, 0x6b, 0x65, 0x65, 0x70, 0x5f, 0x61, 0x6c, 0x69, 0x76, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x09, 0x6b, 0x65, 0x65, 0x70, 0x41, 0x6c, 0x69, 0x76, 0x65, 0x12, 0x1f, 0x0a, 0x0b,
+	0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x70, 0x69, 0x65, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x0a, 0x66, 0x69, 0x72, 0x73, 0x74, 0x50, 0x69, 0x65, 0x63, 0x65, 0x12, 0x1d, 0x0a,
+	0x0a, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x70, 0x69, 0x65, 0x63, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x09, 0x6c, 0x61, 0x73, 0x74, 0x50, 0x69, 0x65, 0x63, 0x65, 0x22, 0xeb, 0x02, 0x0a,
+	0x03, 0x4a, 0x6f, 0x62, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x02, 0x69, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x69, 0x6e, 0x66, 0x6f, 0x5f, 0x68, 0x61, 0x73,
+	0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x6e, 0x66, 0x6f, 0x48, 0x61, 0x73,
+	0x68, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x70, 0x61, 0x74, 0x68, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74,
+	0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74,
+	0x79, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x14, 0x0a,
+	0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x66, 0x69,
+	0x6c, 0x65, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x64,
+	0x5f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0e, 0x63, 0x6f,
+	0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05,
+	0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x62, 0x79, 0x74,
+	0x65, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x5f,
+	0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x63, 0x6f, 0x6d,
+	0x70, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x63,
+	0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x74,
+	0x61, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09,
+	0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x66, 0x69, 0x6e,
+	0x69, 0x73, 0x68, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a,
+	0x66, 0x69, 0x6e, 0x69, 0x73, 0x68, 0x65, 0x64, 0x41, 0x74, 0x22, 0x5f, 0x0a, 0x10, 0x53, 0x75,
+	0x62, 0x6d, 0x69, 0x74, 0x4a, 0x6f, 0x62, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b,
+	0x0a, 0x09, 0x69, 0x6e, 0x66, 0x6f, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x69, 0x6e, 0x66, 0x6f, 0x48, 0x61, 0x73, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x70,
+	0x61, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x12,
+	0x1a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x22, 0x28, 0x0a, 0x0e, 0x53,
+	0x75, 0x62, 0x6d, 0x69, 0x74, 0x4a, 0x6f, 0x62, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x16, 0x0a,
+	0x03, 0x6a, 0x6f, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x4a, 0x6f, 0x62,
+	0x52, 0x03, 0x6a, 0x6f, 0x62, 0x22, 0x22, 0x0a, 0x10, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x4a,
+	0x6f, 0x62, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x10, 0x0a, 0x0e, 0x43, 0x61, 0x6e,
+	0x63, 0x65, 0x6c, 0x4a, 0x6f, 0x62, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x11, 0x0a, 0x0f, 0x4c,
+	0x69, 0x73, 0x74, 0x4a, 0x6f, 0x62, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x29,
+	0x0a, 0x0d, 0x4c, 0x69, 0x73, 0x74, 0x4a, 0x6f, 0x62, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
+	0x18, 0x0a, 0x04, 0x6a, 0x6f, 0x62, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x04, 0x2e,
+	0x4a, 0x6f, 0x62, 0x52, 0x04, 0x6a, 0x6f, 0x62, 0x73, 0x32, 0xf2, 0x04, 0x0a, 0x10, 0x54, 0x6f,
+	0x72, 0x72, 0x65, 0x6e, 0x74, 0x57, 0x65, 0x62, 0x53, 0x65, 0x65, 0x64, 0x65, 0x72, 0x12, 0x22,
+	0x0a, 0x04, 0x53, 0x74, 0x61, 0x74, 0x12, 0x0c, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x0a, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79,
+	0x22, 0x00, 0x12, 0x2a, 0x0a, 0x0a, 0x53, 0x74, 0x61, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x12, 0x0c, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0a,
+	0x2e, 0x53, 0x74, 0x61, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x30, 0x01, 0x12, 0x25,
+	0x0a, 0x05, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x0d, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0b, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x52, 0x65,
+	0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x31, 0x0a, 0x09, 0x41, 0x64, 0x64, 0x4d, 0x61, 0x67, 0x6e,
+	0x65, 0x74, 0x12, 0x11, 0x2e, 0x41, 0x64, 0x64, 0x4d, 0x61, 0x67, 0x6e, 0x65, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x41, 0x64, 0x64, 0x4d, 0x61, 0x67, 0x6e, 0x65,
+	0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x3a, 0x0a, 0x0c, 0x4c, 0x69, 0x73, 0x74,
+	0x54, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x14, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x54,
+	0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12,
+	0x2e, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x22, 0x00, 0x12, 0x37, 0x0a, 0x0b, 0x44, 0x72, 0x6f, 0x70, 0x54, 0x6f, 0x72, 0x72,
+	0x65, 0x6e, 0x74, 0x12, 0x13, 0x2e, 0x44, 0x72, 0x6f, 0x70, 0x54, 0x6f, 0x72, 0x72, 0x65, 0x6e,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x44, 0x72, 0x6f, 0x70, 0x54,
+	0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x34, 0x0a,
+	0x0a, 0x50, 0x69, 0x6e, 0x54, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x12, 0x12, 0x2e, 0x50, 0x69,
+	0x6e, 0x54, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x10, 0x2e, 0x50, 0x69, 0x6e, 0x54, 0x6f, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x70, 0x6c,
+	0x79, 0x22, 0x00, 0x12, 0x2e, 0x0a, 0x08, 0x50, 0x72, 0x65, 0x66, 0x65, 0x74, 0x63, 0x68, 0x12,
+	0x10, 0x2e, 0x50, 0x72, 0x65, 0x66, 0x65, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x0e, 0x2e, 0x50, 0x72, 0x65, 0x66, 0x65, 0x74, 0x63, 0x68, 0x52, 0x65, 0x70, 0x6c,
+	0x79, 0x22, 0x00, 0x12, 0x43, 0x0a, 0x0f, 0x53, 0x65, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x50, 0x72,
+	0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x17, 0x2e, 0x53, 0x65, 0x74, 0x46, 0x69, 0x6c, 0x65,
+	0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x15, 0x2e, 0x53, 0x65, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74,
+	0x79, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x31, 0x0a, 0x09, 0x53, 0x75, 0x62, 0x6d,
+	0x69, 0x74, 0x4a, 0x6f, 0x62, 0x12, 0x11, 0x2e, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x4a, 0x6f,
+	0x62, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x53, 0x75, 0x62, 0x6d, 0x69,
+	0x74, 0x4a, 0x6f, 0x62, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x31, 0x0a, 0x09, 0x43,
+	0x61, 0x6e, 0x63, 0x65, 0x6c, 0x4a, 0x6f, 0x62, 0x12, 0x11, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65,
+	0x6c, 0x4a, 0x6f, 0x62, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x43, 0x61,
+	0x6e, 0x63, 0x65, 0x6c, 0x4a, 0x6f, 0x62, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x12, 0x2e,
+	0x0a, 0x08, 0x4c, 0x69, 0x73, 0x74, 0x4a, 0x6f, 0x62, 0x73, 0x12, 0x10, 0x2e, 0x4c, 0x69, 0x73,
+	0x74, 0x4a, 0x6f, 0x62, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x4c,
+	0x69, 0x73, 0x74, 0x4a, 0x6f, 0x62, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x22, 0x00, 0x42, 0x04,
+	0x5a, 0x02, 0x2e, 0x2f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -538,34 +1887,80 @@ func file_proto_torrent_web_seeder_proto_rawDescGZIP() []byte {
 	return file_proto_torrent_web_seeder_proto_rawDescData
 }
 
-var file_proto_torrent_web_seeder_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_proto_torrent_web_seeder_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_proto_torrent_web_seeder_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_proto_torrent_web_seeder_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
 var file_proto_torrent_web_seeder_proto_goTypes = []any{
-	(StatReply_Status)(0), // 0: StatReply.Status
-	(Piece_Priority)(0),   // 1: Piece.Priority
-	(*StatRequest)(nil),   // 2: StatRequest
-	(*StatReply)(nil),     // 3: StatReply
-	(*Piece)(nil),         // 4: Piece
-	(*FilesRequest)(nil),  // 5: FilesRequest
-	(*File)(nil),          // 6: File
-	(*FilesReply)(nil),    // 7: FilesReply
+	(StatReply_Status)(0),          // 0: StatReply.Status
+	(Piece_Priority)(0),            // 1: Piece.Priority
+	(File_Priority)(0),             // 2: File.Priority
+	(*StatRequest)(nil),            // 3: StatRequest
+	(*StatReply)(nil),              // 4: StatReply
+	(*Piece)(nil),                  // 5: Piece
+	(*FilesRequest)(nil),           // 6: FilesRequest
+	(*File)(nil),                   // 7: File
+	(*FilesReply)(nil),             // 8: FilesReply
+	(*AddMagnetRequest)(nil),       // 9: AddMagnetRequest
+	(*AddMagnetReply)(nil),         // 10: AddMagnetReply
+	(*ListTorrentsRequest)(nil),    // 11: ListTorrentsRequest
+	(*ActiveTorrent)(nil),          // 12: ActiveTorrent
+	(*ListTorrentsReply)(nil),      // 13: ListTorrentsReply
+	(*DropTorrentRequest)(nil),     // 14: DropTorrentRequest
+	(*DropTorrentReply)(nil),       // 15: DropTorrentReply
+	(*PinTorrentRequest)(nil),      // 16: PinTorrentRequest
+	(*PinTorrentReply)(nil),        // 17: PinTorrentReply
+	(*SetFilePriorityRequest)(nil), // 18: SetFilePriorityRequest
+	(*SetFilePriorityReply)(nil),   // 19: SetFilePriorityReply
+	(*PrefetchRequest)(nil),        // 20: PrefetchRequest
+	(*PrefetchReply)(nil),          // 21: PrefetchReply
+	(*Job)(nil),                    // 22: Job
+	(*SubmitJobRequest)(nil),       // 23: SubmitJobRequest
+	(*SubmitJobReply)(nil),         // 24: SubmitJobReply
+	(*CancelJobRequest)(nil),       // 25: CancelJobRequest
+	(*CancelJobReply)(nil),         // 26: CancelJobReply
+	(*ListJobsRequest)(nil),        // 27: ListJobsRequest
+	(*ListJobsReply)(nil),          // 28: ListJobsReply
 }
 var file_proto_torrent_web_seeder_proto_depIdxs = []int32{
-	0, // 0: StatReply.status:type_name -> StatReply.Status
-	4, // 1: StatReply.pieces:type_name -> Piece
-	1, // 2: Piece.priority:type_name -> Piece.Priority
-	6, // 3: FilesReply.files:type_name -> File
-	2, // 4: TorrentWebSeeder.Stat:input_type -> StatRequest
-	2, // 5: TorrentWebSeeder.StatStream:input_type -> StatRequest
-	5, // 6: TorrentWebSeeder.Files:input_type -> FilesRequest
-	3, // 7: TorrentWebSeeder.Stat:output_type -> StatReply
-	3, // 8: TorrentWebSeeder.StatStream:output_type -> StatReply
-	7, // 9: TorrentWebSeeder.Files:output_type -> FilesReply
-	7, // [7:10] is the sub-list for method output_type
-	4, // [4:7] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	0,  // 0: StatReply.status:type_name -> StatReply.Status
+	5,  // 1: StatReply.pieces:type_name -> Piece
+	2,  // 2: StatReply.priority:type_name -> File.Priority
+	1,  // 3: Piece.priority:type_name -> Piece.Priority
+	2,  // 4: File.priority:type_name -> File.Priority
+	7,  // 5: FilesReply.files:type_name -> File
+	7,  // 6: AddMagnetReply.files:type_name -> File
+	12, // 7: ListTorrentsReply.torrents:type_name -> ActiveTorrent
+	2,  // 8: SetFilePriorityRequest.priority:type_name -> File.Priority
+	22, // 9: SubmitJobReply.job:type_name -> Job
+	22, // 10: ListJobsReply.jobs:type_name -> Job
+	3,  // 11: TorrentWebSeeder.Stat:input_type -> StatRequest
+	3,  // 12: TorrentWebSeeder.StatStream:input_type -> StatRequest
+	6,  // 13: TorrentWebSeeder.Files:input_type -> FilesRequest
+	9,  // 14: TorrentWebSeeder.AddMagnet:input_type -> AddMagnetRequest
+	11, // 15: TorrentWebSeeder.ListTorrents:input_type -> ListTorrentsRequest
+	14, // 16: TorrentWebSeeder.DropTorrent:input_type -> DropTorrentRequest
+	16, // 17: TorrentWebSeeder.PinTorrent:input_type -> PinTorrentRequest
+	20, // 18: TorrentWebSeeder.Prefetch:input_type -> PrefetchRequest
+	18, // 19: TorrentWebSeeder.SetFilePriority:input_type -> SetFilePriorityRequest
+	23, // 20: TorrentWebSeeder.SubmitJob:input_type -> SubmitJobRequest
+	25, // 21: TorrentWebSeeder.CancelJob:input_type -> CancelJobRequest
+	27, // 22: TorrentWebSeeder.ListJobs:input_type -> ListJobsRequest
+	4,  // 23: TorrentWebSeeder.Stat:output_type -> StatReply
+	4,  // 24: TorrentWebSeeder.StatStream:output_type -> StatReply
+	8,  // 25: TorrentWebSeeder.Files:output_type -> FilesReply
+	10, // 26: TorrentWebSeeder.AddMagnet:output_type -> AddMagnetReply
+	13, // 27: TorrentWebSeeder.ListTorrents:output_type -> ListTorrentsReply
+	15, // 28: TorrentWebSeeder.DropTorrent:output_type -> DropTorrentReply
+	17, // 29: TorrentWebSeeder.PinTorrent:output_type -> PinTorrentReply
+	21, // 30: TorrentWebSeeder.Prefetch:output_type -> PrefetchReply
+	19, // 31: TorrentWebSeeder.SetFilePriority:output_type -> SetFilePriorityReply
+	24, // 32: TorrentWebSeeder.SubmitJob:output_type -> SubmitJobReply
+	26, // 33: TorrentWebSeeder.CancelJob:output_type -> CancelJobReply
+	28, // 34: TorrentWebSeeder.ListJobs:output_type -> ListJobsReply
+	23, // [23:35] is the sub-list for method output_type
+	11, // [11:23] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_proto_torrent_web_seeder_proto_init() }
@@ -578,8 +1973,8 @@ func file_proto_torrent_web_seeder_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proto_torrent_web_seeder_proto_rawDesc,
-			NumEnums:      2,
-			NumMessages:   6,
+			NumEnums:      3,
+			NumMessages:   26,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
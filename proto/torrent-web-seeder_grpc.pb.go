@@ -19,9 +19,18 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	TorrentWebSeeder_Stat_FullMethodName       = "/TorrentWebSeeder/Stat"
-	TorrentWebSeeder_StatStream_FullMethodName = "/TorrentWebSeeder/StatStream"
-	TorrentWebSeeder_Files_FullMethodName      = "/TorrentWebSeeder/Files"
+	TorrentWebSeeder_Stat_FullMethodName            = "/TorrentWebSeeder/Stat"
+	TorrentWebSeeder_StatStream_FullMethodName      = "/TorrentWebSeeder/StatStream"
+	TorrentWebSeeder_Files_FullMethodName           = "/TorrentWebSeeder/Files"
+	TorrentWebSeeder_AddMagnet_FullMethodName       = "/TorrentWebSeeder/AddMagnet"
+	TorrentWebSeeder_ListTorrents_FullMethodName    = "/TorrentWebSeeder/ListTorrents"
+	TorrentWebSeeder_DropTorrent_FullMethodName     = "/TorrentWebSeeder/DropTorrent"
+	TorrentWebSeeder_PinTorrent_FullMethodName      = "/TorrentWebSeeder/PinTorrent"
+	TorrentWebSeeder_Prefetch_FullMethodName        = "/TorrentWebSeeder/Prefetch"
+	TorrentWebSeeder_SetFilePriority_FullMethodName = "/TorrentWebSeeder/SetFilePriority"
+	TorrentWebSeeder_SubmitJob_FullMethodName       = "/TorrentWebSeeder/SubmitJob"
+	TorrentWebSeeder_CancelJob_FullMethodName       = "/TorrentWebSeeder/CancelJob"
+	TorrentWebSeeder_ListJobs_FullMethodName        = "/TorrentWebSeeder/ListJobs"
 )
 
 // TorrentWebSeederClient is the client API for TorrentWebSeeder service.
@@ -34,6 +43,24 @@ type TorrentWebSeederClient interface {
 	StatStream(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatReply], error)
 	// Get file list
 	Files(ctx context.Context, in *FilesRequest, opts ...grpc.CallOption) (*FilesReply, error)
+	// Add magnet and wait for its metadata
+	AddMagnet(ctx context.Context, in *AddMagnetRequest, opts ...grpc.CallOption) (*AddMagnetReply, error)
+	// List active torrents (admin)
+	ListTorrents(ctx context.Context, in *ListTorrentsRequest, opts ...grpc.CallOption) (*ListTorrentsReply, error)
+	// Drop active torrent (admin)
+	DropTorrent(ctx context.Context, in *DropTorrentRequest, opts ...grpc.CallOption) (*DropTorrentReply, error)
+	// Pin or unpin active torrent (admin)
+	PinTorrent(ctx context.Context, in *PinTorrentRequest, opts ...grpc.CallOption) (*PinTorrentReply, error)
+	// Start downloading file head and tail or byte range
+	Prefetch(ctx context.Context, in *PrefetchRequest, opts ...grpc.CallOption) (*PrefetchReply, error)
+	// Set file download priority
+	SetFilePriority(ctx context.Context, in *SetFilePriorityRequest, opts ...grpc.CallOption) (*SetFilePriorityReply, error)
+	// Submit background download job (admin)
+	SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*SubmitJobReply, error)
+	// Cancel background download job (admin)
+	CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobReply, error)
+	// List background download jobs (admin)
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsReply, error)
 }
 
 type torrentWebSeederClient struct {
@@ -83,6 +110,96 @@ func (c *torrentWebSeederClient) Files(ctx context.Context, in *FilesRequest, op
 	return out, nil
 }
 
+func (c *torrentWebSeederClient) AddMagnet(ctx context.Context, in *AddMagnetRequest, opts ...grpc.CallOption) (*AddMagnetReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddMagnetReply)
+	err := c.cc.Invoke(ctx, TorrentWebSeeder_AddMagnet_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *torrentWebSeederClient) ListTorrents(ctx context.Context, in *ListTorrentsRequest, opts ...grpc.CallOption) (*ListTorrentsReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTorrentsReply)
+	err := c.cc.Invoke(ctx, TorrentWebSeeder_ListTorrents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *torrentWebSeederClient) DropTorrent(ctx context.Context, in *DropTorrentRequest, opts ...grpc.CallOption) (*DropTorrentReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DropTorrentReply)
+	err := c.cc.Invoke(ctx, TorrentWebSeeder_DropTorrent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *torrentWebSeederClient) PinTorrent(ctx context.Context, in *PinTorrentRequest, opts ...grpc.CallOption) (*PinTorrentReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PinTorrentReply)
+	err := c.cc.Invoke(ctx, TorrentWebSeeder_PinTorrent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *torrentWebSeederClient) Prefetch(ctx context.Context, in *PrefetchRequest, opts ...grpc.CallOption) (*PrefetchReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PrefetchReply)
+	err := c.cc.Invoke(ctx, TorrentWebSeeder_Prefetch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *torrentWebSeederClient) SetFilePriority(ctx context.Context, in *SetFilePriorityRequest, opts ...grpc.CallOption) (*SetFilePriorityReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetFilePriorityReply)
+	err := c.cc.Invoke(ctx, TorrentWebSeeder_SetFilePriority_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *torrentWebSeederClient) SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*SubmitJobReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitJobReply)
+	err := c.cc.Invoke(ctx, TorrentWebSeeder_SubmitJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *torrentWebSeederClient) CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelJobReply)
+	err := c.cc.Invoke(ctx, TorrentWebSeeder_CancelJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *torrentWebSeederClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListJobsReply)
+	err := c.cc.Invoke(ctx, TorrentWebSeeder_ListJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TorrentWebSeederServer is the server API for TorrentWebSeeder service.
 // All implementations must embed UnimplementedTorrentWebSeederServer
 // for forward compatibility.
@@ -93,6 +210,24 @@ type TorrentWebSeederServer interface {
 	StatStream(*StatRequest, grpc.ServerStreamingServer[StatReply]) error
 	// Get file list
 	Files(context.Context, *FilesRequest) (*FilesReply, error)
+	// Add magnet and wait for its metadata
+	AddMagnet(context.Context, *AddMagnetRequest) (*AddMagnetReply, error)
+	// List active torrents (admin)
+	ListTorrents(context.Context, *ListTorrentsRequest) (*ListTorrentsReply, error)
+	// Drop active torrent (admin)
+	DropTorrent(context.Context, *DropTorrentRequest) (*DropTorrentReply, error)
+	// Pin or unpin active torrent (admin)
+	PinTorrent(context.Context, *PinTorrentRequest) (*PinTorrentReply, error)
+	// Start downloading file head and tail or byte range
+	Prefetch(context.Context, *PrefetchRequest) (*PrefetchReply, error)
+	// Set file download priority
+	SetFilePriority(context.Context, *SetFilePriorityRequest) (*SetFilePriorityReply, error)
+	// Submit background download job (admin)
+	SubmitJob(context.Context, *SubmitJobRequest) (*SubmitJobReply, error)
+	// Cancel background download job (admin)
+	CancelJob(context.Context, *CancelJobRequest) (*CancelJobReply, error)
+	// List background download jobs (admin)
+	ListJobs(context.Context, *ListJobsRequest) (*ListJobsReply, error)
 	mustEmbedUnimplementedTorrentWebSeederServer()
 }
 
@@ -112,6 +247,33 @@ func (UnimplementedTorrentWebSeederServer) StatStream(*StatRequest, grpc.ServerS
 func (UnimplementedTorrentWebSeederServer) Files(context.Context, *FilesRequest) (*FilesReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Files not implemented")
 }
+func (UnimplementedTorrentWebSeederServer) AddMagnet(context.Context, *AddMagnetRequest) (*AddMagnetReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddMagnet not implemented")
+}
+func (UnimplementedTorrentWebSeederServer) ListTorrents(context.Context, *ListTorrentsRequest) (*ListTorrentsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTorrents not implemented")
+}
+func (UnimplementedTorrentWebSeederServer) DropTorrent(context.Context, *DropTorrentRequest) (*DropTorrentReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DropTorrent not implemented")
+}
+func (UnimplementedTorrentWebSeederServer) PinTorrent(context.Context, *PinTorrentRequest) (*PinTorrentReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PinTorrent not implemented")
+}
+func (UnimplementedTorrentWebSeederServer) Prefetch(context.Context, *PrefetchRequest) (*PrefetchReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Prefetch not implemented")
+}
+func (UnimplementedTorrentWebSeederServer) SetFilePriority(context.Context, *SetFilePriorityRequest) (*SetFilePriorityReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFilePriority not implemented")
+}
+func (UnimplementedTorrentWebSeederServer) SubmitJob(context.Context, *SubmitJobRequest) (*SubmitJobReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitJob not implemented")
+}
+func (UnimplementedTorrentWebSeederServer) CancelJob(context.Context, *CancelJobRequest) (*CancelJobReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelJob not implemented")
+}
+func (UnimplementedTorrentWebSeederServer) ListJobs(context.Context, *ListJobsRequest) (*ListJobsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobs not implemented")
+}
 func (UnimplementedTorrentWebSeederServer) mustEmbedUnimplementedTorrentWebSeederServer() {}
 func (UnimplementedTorrentWebSeederServer) testEmbeddedByValue()                          {}
 
@@ -180,6 +342,168 @@ func _TorrentWebSeeder_Files_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TorrentWebSeeder_AddMagnet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddMagnetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TorrentWebSeederServer).AddMagnet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TorrentWebSeeder_AddMagnet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TorrentWebSeederServer).AddMagnet(ctx, req.(*AddMagnetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TorrentWebSeeder_ListTorrents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTorrentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TorrentWebSeederServer).ListTorrents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TorrentWebSeeder_ListTorrents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TorrentWebSeederServer).ListTorrents(ctx, req.(*ListTorrentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TorrentWebSeeder_DropTorrent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DropTorrentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TorrentWebSeederServer).DropTorrent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TorrentWebSeeder_DropTorrent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TorrentWebSeederServer).DropTorrent(ctx, req.(*DropTorrentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TorrentWebSeeder_PinTorrent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PinTorrentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TorrentWebSeederServer).PinTorrent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TorrentWebSeeder_PinTorrent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TorrentWebSeederServer).PinTorrent(ctx, req.(*PinTorrentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TorrentWebSeeder_Prefetch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrefetchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TorrentWebSeederServer).Prefetch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TorrentWebSeeder_Prefetch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TorrentWebSeederServer).Prefetch(ctx, req.(*PrefetchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TorrentWebSeeder_SetFilePriority_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetFilePriorityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TorrentWebSeederServer).SetFilePriority(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TorrentWebSeeder_SetFilePriority_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TorrentWebSeederServer).SetFilePriority(ctx, req.(*SetFilePriorityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TorrentWebSeeder_SubmitJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TorrentWebSeederServer).SubmitJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TorrentWebSeeder_SubmitJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TorrentWebSeederServer).SubmitJob(ctx, req.(*SubmitJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TorrentWebSeeder_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TorrentWebSeederServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TorrentWebSeeder_CancelJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TorrentWebSeederServer).CancelJob(ctx, req.(*CancelJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TorrentWebSeeder_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TorrentWebSeederServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TorrentWebSeeder_ListJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TorrentWebSeederServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TorrentWebSeeder_ServiceDesc is the grpc.ServiceDesc for TorrentWebSeeder service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -195,6 +519,42 @@ var TorrentWebSeeder_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Files",
 			Handler:    _TorrentWebSeeder_Files_Handler,
 		},
+		{
+			MethodName: "AddMagnet",
+			Handler:    _TorrentWebSeeder_AddMagnet_Handler,
+		},
+		{
+			MethodName: "ListTorrents",
+			Handler:    _TorrentWebSeeder_ListTorrents_Handler,
+		},
+		{
+			MethodName: "DropTorrent",
+			Handler:    _TorrentWebSeeder_DropTorrent_Handler,
+		},
+		{
+			MethodName: "PinTorrent",
+			Handler:    _TorrentWebSeeder_PinTorrent_Handler,
+		},
+		{
+			MethodName: "Prefetch",
+			Handler:    _TorrentWebSeeder_Prefetch_Handler,
+		},
+		{
+			MethodName: "SetFilePriority",
+			Handler:    _TorrentWebSeeder_SetFilePriority_Handler,
+		},
+		{
+			MethodName: "SubmitJob",
+			Handler:    _TorrentWebSeeder_SubmitJob_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _TorrentWebSeeder_CancelJob_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _TorrentWebSeeder_ListJobs_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
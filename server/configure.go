@@ -4,8 +4,6 @@ import (
 	"net/http"
 	"time"
 
-	"code.cloudfoundry.org/bytefmt"
-	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 	cs "github.com/webtor-io/common-services"
@@ -19,14 +17,24 @@ func configure(app *cli.App) {
 	app.Flags = cs.RegisterPromFlags(app.Flags)
 	app.Flags = s.RegisterWebFlags(app.Flags)
 	app.Flags = s.RegisterTorrentClientFlags(app.Flags)
+	app.Flags = s.RegisterStorageFlags(app.Flags)
+	app.Flags = s.RegisterDiskMonitorFlags(app.Flags)
 	app.Flags = s.RegisterTorrentStoreFlags(app.Flags)
 	app.Flags = s.RegisterFileStoreFlags(app.Flags)
 	app.Flags = s.RegisterStatFlags(app.Flags)
 	app.Flags = s.RegisterVaultFlags(app.Flags)
 	app.Flags = s.RegisterWebSeederFlags(app.Flags)
+	app.Flags = s.RegisterTorrentMapFlags(app.Flags)
+	app.Flags = s.RegisterAdminFlags(app.Flags)
+	app.Flags = s.RegisterGCFlags(app.Flags)
+	app.Flags = s.RegisterWarmRestartFlags(app.Flags)
+	app.Flags = s.RegisterPrefetchFlags(app.Flags)
+	app.Flags = s.RegisterJobFlags(app.Flags)
+	app.Flags = s.RegisterWebhookFlags(app.Flags)
 	// app.Flags = s.RegisterTorrentClientPoolFlags(app.Flags)
 	app.Action = run
 	configureDiagnose(app)
+	configureGC(app)
 }
 
 func run(c *cli.Context) error {
@@ -35,8 +43,18 @@ func run(c *cli.Context) error {
 	torrentStore := s.NewTorrentStore(c)
 	defer torrentStore.Close()
 
+	// Setting Webhooks
+	webhooks, err := s.NewWebhooks(c)
+	if err != nil {
+		return err
+	}
+	if webhooks != nil {
+		services = append(services, webhooks)
+		defer webhooks.Close()
+	}
+
 	// Setting TorrentClient
-	torrentClient, err := s.NewTorrentClient(c)
+	torrentClient, err := s.NewTorrentClient(c, webhooks)
 	if err != nil {
 		return err
 	}
@@ -60,15 +78,31 @@ func run(c *cli.Context) error {
 
 	// Setting FileStoreMap
 	fileStoreMap := s.NewFileStoreMap(c)
+	services = append(services, fileStoreMap)
+	defer fileStoreMap.Close()
+
+	// Setting MagnetStoreMap
+	magnetStoreMap := s.NewMagnetStoreMap(c)
 
 	// Setting TouchMap
 	touchMap := s.NewTouchMap(c)
 
 	// Setting TorrentMap
-	torrentMap := s.NewTorrentMap(torrentClient, torrentStoreMap, fileStoreMap)
+	torrentMap := s.NewTorrentMap(c, torrentClient, torrentStoreMap, fileStoreMap, magnetStoreMap, vault, webhooks)
+
+	// Setting Prefetcher
+	prefetcher, err := s.NewPrefetcher(c, torrentMap)
+	if err != nil {
+		return err
+	}
+
+	// Setting JobQueue
+	jobQueue := s.NewJobQueue(c, torrentMap)
+	services = append(services, jobQueue)
+	defer jobQueue.Close()
 
 	// Setting Stat
-	stat := s.NewStat(torrentMap)
+	stat := s.NewStat(c, torrentMap, prefetcher, jobQueue)
 
 	// Setting StatGRPC
 	statGRPC := s.NewStatGRPC(c, stat)
@@ -83,20 +117,41 @@ func run(c *cli.Context) error {
 	fileCacheMap := s.NewFileCacheMap(c)
 
 	// Setting TorrentFileCountMap
-	torrentFileCountMap := s.NewTorrentFileCountMap(fileStoreMap, torrentStoreMap)
+	torrentFileCountMap := s.NewTorrentFileCountMap(fileStoreMap, magnetStoreMap, torrentStoreMap)
 
 	// Setting WebSeeder
-	maxReadahead, err := bytefmt.ToBytes(c.String(s.MaxReadaheadFlag))
+	webSeederConfig, err := s.NewWebSeederConfig(c)
 	if err != nil {
-		return errors.Wrap(err, "failed to parse max readahead flag")
+		return err
 	}
-	webSeeder := s.NewWebSeeder(torrentMap, fileCacheMap, torrentFileCountMap, touchMap, statWeb, vault, cl, int64(maxReadahead))
+	webSeeder := s.NewWebSeeder(torrentMap, fileCacheMap, torrentFileCountMap, touchMap, statWeb, vault, cl, webSeederConfig, prefetcher)
 
 	// Setting Web
 	web := s.NewWeb(c, webSeeder)
 	services = append(services, web)
 	defer web.Close()
 
+	// Setting Admin
+	admin := s.NewAdmin(c, torrentMap, jobQueue)
+	if admin != nil {
+		services = append(services, admin)
+		defer admin.Close()
+	}
+
+	// Setting GC
+	gc := s.NewGC(c, torrentMap)
+	if gc != nil {
+		services = append(services, gc)
+		defer gc.Close()
+	}
+
+	// Setting WarmRestart
+	warmRestart := s.NewWarmRestart(c, torrentMap)
+	if warmRestart != nil {
+		services = append(services, warmRestart)
+		defer warmRestart.Close()
+	}
+
 	// Setting Probe
 	probe := cs.NewProbe(c)
 	if probe != nil {
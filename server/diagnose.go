@@ -26,6 +26,8 @@ func configureDiagnose(app *cli.App) {
 		},
 	}
 	diagnoseFlags = s.RegisterTorrentClientFlags(diagnoseFlags)
+	diagnoseFlags = s.RegisterStorageFlags(diagnoseFlags)
+	diagnoseFlags = s.RegisterTorrentStoreFlags(diagnoseFlags)
 
 	app.Commands = append(app.Commands, cli.Command{
 		Name:      "diagnose",
@@ -55,7 +57,7 @@ func runDiagnose(c *cli.Context) error {
 
 	// Phase 1: Client Init
 	fmt.Println("--- Client Initialization ---")
-	torrentClient, err := s.NewTorrentClient(c)
+	torrentClient, err := s.NewTorrentClient(c, nil)
 	if err != nil {
 		fmt.Printf("[FAIL] Client init error: %v\n", err)
 		return err
@@ -198,6 +200,9 @@ func runDiagnose(c *cli.Context) error {
 			}
 			fmt.Printf("         %s (%s)\n", strings.Join(f.BestPath(), "/"), formatBytes(f.Length))
 		}
+		if isMagnet {
+			pushMetainfo(c, t)
+		}
 	}
 	fmt.Println()
 
@@ -335,6 +340,22 @@ func runDiagnose(c *cli.Context) error {
 	return nil
 }
 
+// pushMetainfo stores metadata resolved from the swarm in the torrent store, if one is configured.
+func pushMetainfo(c *cli.Context, t *torrent.Torrent) {
+	torrentStore := s.NewTorrentStore(c)
+	defer torrentStore.Close()
+	if !torrentStore.Enabled() {
+		return
+	}
+	mi := t.Metainfo()
+	err := s.NewTorrentStoreMap(torrentStore).Push(t.InfoHash().HexString(), &mi)
+	if err != nil {
+		fmt.Printf("[WARN] Failed to push metadata to torrent store: %v\n", err)
+		return
+	}
+	fmt.Println("[OK]   Metadata pushed to torrent store")
+}
+
 type trackerLine struct {
 	url    string
 	status string
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bytefmt"
+	"github.com/urfave/cli"
+	s "github.com/webtor-io/torrent-web-seeder/server/services"
+)
+
+const (
+	GCDryRunFlag = "dry-run"
+)
+
+func configureGC(app *cli.App) {
+	gcFlags := []cli.Flag{
+		cli.StringFlag{
+			Name:   s.DataDirFlag,
+			Usage:  "data dir",
+			Value:  os.TempDir(),
+			EnvVar: "DATA_DIR",
+		},
+		cli.BoolFlag{
+			Name:  GCDryRunFlag,
+			Usage: "only report stale torrent data without removing it",
+		},
+	}
+	gcFlags = s.RegisterGCFlags(gcFlags)
+
+	app.Commands = append(app.Commands, cli.Command{
+		Name:   "gc",
+		Usage:  "Remove torrent data not touched for longer than retention period",
+		Flags:  gcFlags,
+		Action: runGC,
+	})
+}
+
+func runGC(c *cli.Context) error {
+	// Torrents open in a running server are not known here, they are
+	// protected only by their recent .touch mtime.
+	gc := s.NewGCWithRetention(c.String(s.DataDirFlag), c.Duration(s.GCRetentionFlag), nil)
+	dryRun := c.Bool(GCDryRunFlag)
+	report, err := gc.Collect(dryRun)
+	if err != nil {
+		return err
+	}
+	for _, i := range report.Items {
+		fmt.Printf("%v\t%v\t%v\n", i.Dir, i.Touched.Format(time.RFC3339), bytefmt.ByteSize(uint64(i.Bytes)))
+	}
+	action := "reclaimed"
+	if dryRun {
+		action = "would reclaim"
+	}
+	fmt.Printf("%v %v from %v torrent data dirs\n", action, bytefmt.ByteSize(uint64(report.Bytes)), len(report.Items))
+	return nil
+}
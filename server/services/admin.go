@@ -0,0 +1,208 @@
+package services
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	AdminHostFlag  = "admin-host"
+	AdminPortFlag  = "admin-port"
+	AdminTokenFlag = "admin-token"
+)
+
+func RegisterAdminFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.StringFlag{
+			Name:   AdminHostFlag,
+			Usage:  "admin listening host, admin api is only reachable locally by default",
+			Value:  "127.0.0.1",
+			EnvVar: "ADMIN_HOST",
+		},
+		cli.IntFlag{
+			Name:   AdminPortFlag,
+			Usage:  "admin http listening port, 0 disables admin http api",
+			Value:  0,
+			EnvVar: "ADMIN_PORT",
+		},
+		cli.StringFlag{
+			Name:   AdminTokenFlag,
+			Usage:  "token required by admin http and grpc api",
+			Value:  "",
+			EnvVar: "ADMIN_TOKEN",
+		},
+	)
+}
+
+// checkAdminToken compares provided token with configured one in constant time.
+// Empty configured token matches only when allowEmpty is set.
+func checkAdminToken(expected string, provided string, allowEmpty bool) bool {
+	if expected == "" {
+		return allowEmpty
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(provided)) == 1
+}
+
+// Admin serves admin http api on a separate port:
+//
+//	GET    /torrents             list active torrents
+//	DELETE /torrents/{hash}      drop torrent
+//	PUT    /torrents/{hash}/pin  pin torrent
+//	DELETE /torrents/{hash}/pin  unpin torrent
+//	GET    /jobs                 list download jobs
+//	POST   /jobs                 submit download job
+//	GET    /jobs/{id}            get download job
+//	DELETE /jobs/{id}            cancel download job
+type Admin struct {
+	tm    *TorrentMap
+	jq    *JobQueue
+	host  string
+	port  int
+	token string
+	ln    net.Listener
+}
+
+func NewAdmin(c *cli.Context, tm *TorrentMap, jq *JobQueue) *Admin {
+	if c.Int(AdminPortFlag) == 0 {
+		return nil
+	}
+	return &Admin{
+		tm:    tm,
+		jq:    jq,
+		host:  c.String(AdminHostFlag),
+		port:  c.Int(AdminPortFlag),
+		token: c.String(AdminTokenFlag),
+	}
+}
+
+func (s *Admin) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !checkAdminToken(s.token, token, true) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Admin) list(w http.ResponseWriter, _ *http.Request) {
+	err := writeJSON(w, s.tm.Active())
+	if err != nil {
+		log.WithError(err).Error("failed to encode json")
+	}
+}
+
+func (s *Admin) drop(w http.ResponseWriter, r *http.Request) {
+	if !s.tm.Drop(r.PathValue("hash")) {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Admin) pin(pinned bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.tm.Pin(r.PathValue("hash"), pinned) {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Admin) listJobs(w http.ResponseWriter, _ *http.Request) {
+	err := writeJSON(w, s.jq.List())
+	if err != nil {
+		log.WithError(err).Error("failed to encode json")
+	}
+}
+
+// jobRequest is a body of job submit request.
+type jobRequest struct {
+	InfoHash string `json:"info_hash"`
+	Path     string `json:"path"`
+	Priority int    `json:"priority"`
+}
+
+func (s *Admin) submitJob(w http.ResponseWriter, r *http.Request) {
+	var req jobRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "invalid job request", http.StatusBadRequest)
+		return
+	}
+	j, err := s.jq.Submit(req.InfoHash, req.Path, req.Priority)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(j)
+	if err != nil {
+		log.WithError(err).Error("failed to encode json")
+	}
+}
+
+func (s *Admin) getJob(w http.ResponseWriter, r *http.Request) {
+	j, err := s.jq.Get(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	err = writeJSON(w, j)
+	if err != nil {
+		log.WithError(err).Error("failed to encode json")
+	}
+}
+
+func (s *Admin) cancelJob(w http.ResponseWriter, r *http.Request) {
+	err := s.jq.Cancel(r.PathValue("id"))
+	if errors.Is(err, ErrJobNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Admin) Serve() error {
+	addr := fmt.Sprintf("%s:%d", s.host, s.port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.ln = ln
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /torrents", s.auth(s.list))
+	mux.HandleFunc("DELETE /torrents/{hash}", s.auth(s.drop))
+	mux.HandleFunc("PUT /torrents/{hash}/pin", s.auth(s.pin(true)))
+	mux.HandleFunc("DELETE /torrents/{hash}/pin", s.auth(s.pin(false)))
+	mux.HandleFunc("GET /jobs", s.auth(s.listJobs))
+	mux.HandleFunc("POST /jobs", s.auth(s.submitJob))
+	mux.HandleFunc("GET /jobs/{id}", s.auth(s.getJob))
+	mux.HandleFunc("DELETE /jobs/{id}", s.auth(s.cancelJob))
+	log.Infof("serving Admin at %v", addr)
+	if s.token == "" {
+		log.Warnf("admin api at %v accepts requests without token, set --%v", addr, AdminTokenFlag)
+	}
+	return http.Serve(s.ln, RecoverMiddleware(mux))
+}
+
+func (s *Admin) Close() {
+	if s.ln != nil {
+		_ = s.ln.Close()
+	}
+}
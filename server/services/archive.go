@@ -0,0 +1,608 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"container/list"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ArchiveZip = "zip"
+	ArchiveTar = "tar"
+)
+
+var archiveContentTypes = map[string]string{
+	ArchiveZip: "application/zip",
+	ArchiveTar: "application/x-tar",
+}
+
+// archiveSegment is a contiguous part of an archive. Segments are opened
+// lazily at an arbitrary offset, so the archive can be read from any position.
+type archiveSegment struct {
+	offset int64
+	size   int64
+	open   func(off int64) (io.ReadCloser, error)
+}
+
+func newBytesSegment(b []byte) archiveSegment {
+	return archiveSegment{
+		size: int64(len(b)),
+		open: func(off int64) (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(b[off:])), nil
+		},
+	}
+}
+
+// archiveReader concatenates segments into a single io.ReadSeekCloser
+// suitable for http.ServeContent.
+type archiveReader struct {
+	segments []archiveSegment
+	size     int64
+	pos      int64
+	cur      io.ReadCloser
+	curIdx   int
+	curPos   int64
+}
+
+func newArchiveReader(segments []archiveSegment) *archiveReader {
+	var off int64
+	for i := range segments {
+		segments[i].offset = off
+		off += segments[i].size
+	}
+	return &archiveReader{
+		segments: segments,
+		size:     off,
+		curIdx:   -1,
+	}
+}
+
+func (s *archiveReader) Read(p []byte) (int, error) {
+	if s.pos >= s.size {
+		return 0, io.EOF
+	}
+	i := sort.Search(len(s.segments), func(i int) bool {
+		return s.segments[i].offset+s.segments[i].size > s.pos
+	})
+	seg := s.segments[i]
+	if s.cur == nil || s.curIdx != i || s.curPos != s.pos {
+		if s.cur != nil {
+			_ = s.cur.Close()
+			s.cur = nil
+		}
+		r, err := seg.open(s.pos - seg.offset)
+		if err != nil {
+			return 0, err
+		}
+		s.cur = r
+		s.curIdx = i
+		s.curPos = s.pos
+	}
+	left := seg.offset + seg.size - s.pos
+	if int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := s.cur.Read(p)
+	s.pos += int64(n)
+	s.curPos = s.pos
+	if err == io.EOF {
+		if int64(n) < left {
+			return n, io.ErrUnexpectedEOF
+		}
+		err = nil
+	}
+	return n, err
+}
+
+func (s *archiveReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = s.pos + offset
+	case io.SeekEnd:
+		pos = s.size + offset
+	default:
+		return 0, errors.Errorf("invalid whence %v", whence)
+	}
+	if pos < 0 {
+		return 0, errors.New("negative position")
+	}
+	s.pos = pos
+	return pos, nil
+}
+
+func (s *archiveReader) Close() error {
+	if s.cur != nil {
+		return s.cur.Close()
+	}
+	return nil
+}
+
+// archiveFile is a torrent file included into an archive.
+type archiveFile struct {
+	name   string
+	path   string
+	length int64
+	open   func(off int64) (io.ReadCloser, error)
+}
+
+// zipEntry tracks CRC32 of a stored file. CRC is calculated on the fly while
+// the file is streamed from the beginning, or by a separate full read when
+// data descriptor or central directory is requested before that.
+type zipEntry struct {
+	archiveFile
+	offset int64
+	crcs   *crcCache
+	hash   string
+}
+
+const crcCacheCapacity = 10000
+
+// crcCache keeps CRC32 of recently archived files in memory, least recently
+// used entries are dropped first. CRCs are also persisted in torrent
+// .torrent.db, so they survive eviction from cache and restarts.
+type crcCache struct {
+	mux      sync.Mutex
+	capacity int
+	ll       *list.List
+	m        map[string]*list.Element
+	store    *FileCacheMap
+}
+
+type crcCacheEntry struct {
+	key string
+	crc uint32
+}
+
+// newCRCCache creates cache of given capacity, store persists CRCs (nil =
+// memory only).
+func newCRCCache(capacity int, store *FileCacheMap) *crcCache {
+	return &crcCache{
+		capacity: capacity,
+		ll:       list.New(),
+		m:        map[string]*list.Element{},
+		store:    store,
+	}
+}
+
+func (s *crcCache) get(h string, path string) (uint32, bool) {
+	key := h + "/" + path
+	s.mux.Lock()
+	if el, ok := s.m[key]; ok {
+		s.ll.MoveToFront(el)
+		c := el.Value.(*crcCacheEntry).crc
+		s.mux.Unlock()
+		return c, true
+	}
+	s.mux.Unlock()
+	if s.store == nil {
+		return 0, false
+	}
+	c, ok, err := s.store.GetCRC(h, path)
+	if err != nil {
+		log.WithError(err).Warnf("failed to load crc for %v", key)
+		return 0, false
+	}
+	if ok {
+		s.add(key, c)
+	}
+	return c, ok
+}
+
+func (s *crcCache) set(h string, path string, c uint32) {
+	s.add(h+"/"+path, c)
+	if s.store == nil {
+		return
+	}
+	if err := s.store.SetCRC(h, path, c); err != nil {
+		log.WithError(err).Warnf("failed to store crc for %v/%v", h, path)
+	}
+}
+
+func (s *crcCache) add(key string, c uint32) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if el, ok := s.m[key]; ok {
+		el.Value.(*crcCacheEntry).crc = c
+		s.ll.MoveToFront(el)
+		return
+	}
+	s.m[key] = s.ll.PushFront(&crcCacheEntry{key: key, crc: c})
+	for s.ll.Len() > s.capacity {
+		el := s.ll.Back()
+		s.ll.Remove(el)
+		delete(s.m, el.Value.(*crcCacheEntry).key)
+	}
+}
+
+func (s *zipEntry) crc() (uint32, error) {
+	if c, ok := s.crcs.get(s.hash, s.path); ok {
+		return c, nil
+	}
+	r, err := s.open(0)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+	h := crc32.NewIEEE()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to calculate crc for %v", s.name)
+	}
+	if n != s.length {
+		return 0, errors.Errorf("short read while calculating crc for %v", s.name)
+	}
+	c := h.Sum32()
+	s.crcs.set(s.hash, s.path, c)
+	return c, nil
+}
+
+// crcReader calculates CRC32 of the data passing through it, if it was opened
+// at the beginning of the file.
+type crcReader struct {
+	io.ReadCloser
+	e   *zipEntry
+	h   hash.Hash32
+	pos int64
+}
+
+func (s *crcReader) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if n > 0 {
+		_, _ = s.h.Write(p[:n])
+		s.pos += int64(n)
+		if s.pos == s.e.length {
+			s.e.crcs.set(s.e.hash, s.e.path, s.h.Sum32())
+		}
+	}
+	return n, err
+}
+
+func (s *zipEntry) openContent(off int64) (io.ReadCloser, error) {
+	r, err := s.open(off)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.crcs.get(s.hash, s.path); ok || off != 0 {
+		return r, nil
+	}
+	return &crcReader{ReadCloser: r, e: s, h: crc32.NewIEEE()}, nil
+}
+
+const (
+	zipVersion      = 45
+	zipFlags        = 0x0808 // data descriptor + UTF-8 names
+	zipDosDate      = 0x0021 // 1980-01-01
+	zipLocalLen     = 30
+	zipLocalExtra   = 20
+	zipDescLen      = 24
+	zipCentralLen   = 46
+	zipCentralExtra = 28
+	zipEndLen       = 56 + 20 + 22
+	zipMax32        = 0xffffffff
+	zipMax16        = 0xffff
+)
+
+func (s *zipEntry) localHeader() []byte {
+	b := make([]byte, 0, zipLocalLen+len(s.name)+zipLocalExtra)
+	b = binary.LittleEndian.AppendUint32(b, 0x04034b50)
+	b = binary.LittleEndian.AppendUint16(b, zipVersion)
+	b = binary.LittleEndian.AppendUint16(b, zipFlags)
+	b = binary.LittleEndian.AppendUint16(b, 0) // store
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, zipDosDate)
+	b = binary.LittleEndian.AppendUint32(b, 0) // crc is in data descriptor
+	b = binary.LittleEndian.AppendUint32(b, zipMax32)
+	b = binary.LittleEndian.AppendUint32(b, zipMax32)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(s.name)))
+	b = binary.LittleEndian.AppendUint16(b, zipLocalExtra)
+	b = append(b, s.name...)
+	b = binary.LittleEndian.AppendUint16(b, 0x0001)
+	b = binary.LittleEndian.AppendUint16(b, 16)
+	b = binary.LittleEndian.AppendUint64(b, uint64(s.length))
+	b = binary.LittleEndian.AppendUint64(b, uint64(s.length))
+	return b
+}
+
+func (s *zipEntry) dataDescriptor() ([]byte, error) {
+	c, err := s.crc()
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, zipDescLen)
+	b = binary.LittleEndian.AppendUint32(b, 0x08074b50)
+	b = binary.LittleEndian.AppendUint32(b, c)
+	b = binary.LittleEndian.AppendUint64(b, uint64(s.length))
+	b = binary.LittleEndian.AppendUint64(b, uint64(s.length))
+	return b, nil
+}
+
+func (s *zipEntry) centralHeader() ([]byte, error) {
+	c, err := s.crc()
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, zipCentralLen+len(s.name)+zipCentralExtra)
+	b = binary.LittleEndian.AppendUint32(b, 0x02014b50)
+	b = binary.LittleEndian.AppendUint16(b, zipVersion)
+	b = binary.LittleEndian.AppendUint16(b, zipVersion)
+	b = binary.LittleEndian.AppendUint16(b, zipFlags)
+	b = binary.LittleEndian.AppendUint16(b, 0) // store
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, zipDosDate)
+	b = binary.LittleEndian.AppendUint32(b, c)
+	b = binary.LittleEndian.AppendUint32(b, zipMax32)
+	b = binary.LittleEndian.AppendUint32(b, zipMax32)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(s.name)))
+	b = binary.LittleEndian.AppendUint16(b, zipCentralExtra)
+	b = binary.LittleEndian.AppendUint16(b, 0) // comment
+	b = binary.LittleEndian.AppendUint16(b, 0) // disk
+	b = binary.LittleEndian.AppendUint16(b, 0) // internal attrs
+	b = binary.LittleEndian.AppendUint32(b, 0) // external attrs
+	b = binary.LittleEndian.AppendUint32(b, zipMax32)
+	b = append(b, s.name...)
+	b = binary.LittleEndian.AppendUint16(b, 0x0001)
+	b = binary.LittleEndian.AppendUint16(b, 24)
+	b = binary.LittleEndian.AppendUint64(b, uint64(s.length))
+	b = binary.LittleEndian.AppendUint64(b, uint64(s.length))
+	b = binary.LittleEndian.AppendUint64(b, uint64(s.offset))
+	return b, nil
+}
+
+func zipEnd(entries int, cdOffset int64, cdSize int64) []byte {
+	b := make([]byte, 0, zipEndLen)
+	// zip64 end of central directory record
+	b = binary.LittleEndian.AppendUint32(b, 0x06064b50)
+	b = binary.LittleEndian.AppendUint64(b, 44)
+	b = binary.LittleEndian.AppendUint16(b, zipVersion)
+	b = binary.LittleEndian.AppendUint16(b, zipVersion)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint64(b, uint64(entries))
+	b = binary.LittleEndian.AppendUint64(b, uint64(entries))
+	b = binary.LittleEndian.AppendUint64(b, uint64(cdSize))
+	b = binary.LittleEndian.AppendUint64(b, uint64(cdOffset))
+	// zip64 end of central directory locator
+	b = binary.LittleEndian.AppendUint32(b, 0x07064b50)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint64(b, uint64(cdOffset+cdSize))
+	b = binary.LittleEndian.AppendUint32(b, 1)
+	// end of central directory record
+	b = binary.LittleEndian.AppendUint32(b, 0x06054b50)
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, zipMax16)
+	b = binary.LittleEndian.AppendUint16(b, zipMax16)
+	b = binary.LittleEndian.AppendUint32(b, zipMax32)
+	b = binary.LittleEndian.AppendUint32(b, zipMax32)
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	return b
+}
+
+// zipSegments lays out a ZIP64 archive in store mode. The layout depends only
+// on file names and lengths, so the exact archive size is known upfront.
+func zipSegments(h string, files []archiveFile, crcs *crcCache) []archiveSegment {
+	var segments []archiveSegment
+	var entries []*zipEntry
+	var off int64
+	for _, f := range files {
+		e := &zipEntry{
+			archiveFile: f,
+			offset:      off,
+			crcs:        crcs,
+			hash:        h,
+		}
+		entries = append(entries, e)
+		lh := e.localHeader()
+		segments = append(segments, newBytesSegment(lh), archiveSegment{
+			size: f.length,
+			open: e.openContent,
+		}, archiveSegment{
+			size: zipDescLen,
+			open: func(off int64) (io.ReadCloser, error) {
+				b, err := e.dataDescriptor()
+				if err != nil {
+					return nil, err
+				}
+				return io.NopCloser(bytes.NewReader(b[off:])), nil
+			},
+		})
+		off += int64(len(lh)) + f.length + zipDescLen
+	}
+	var cdSize int64
+	for _, e := range entries {
+		cdSize += int64(zipCentralLen + len(e.name) + zipCentralExtra)
+	}
+	cdOffset := off
+	segments = append(segments, archiveSegment{
+		size: cdSize + zipEndLen,
+		open: func(off int64) (io.ReadCloser, error) {
+			var b []byte
+			for _, e := range entries {
+				ch, err := e.centralHeader()
+				if err != nil {
+					return nil, err
+				}
+				b = append(b, ch...)
+			}
+			b = append(b, zipEnd(len(entries), cdOffset, cdSize)...)
+			return io.NopCloser(bytes.NewReader(b[off:])), nil
+		},
+	})
+	return segments
+}
+
+const tarBlockSize = 512
+
+// tarSegments lays out a tar archive. Member headers have fixed size for
+// a given name, so offsets of all members are known upfront.
+func tarSegments(files []archiveFile) ([]archiveSegment, error) {
+	var segments []archiveSegment
+	for _, f := range files {
+		var b bytes.Buffer
+		tw := tar.NewWriter(&b)
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     f.name,
+			Size:     f.length,
+			Mode:     0644,
+			ModTime:  time.Unix(0, 0),
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to write tar header for %v", f.name)
+		}
+		segments = append(segments, newBytesSegment(b.Bytes()), archiveSegment{
+			size: f.length,
+			open: f.open,
+		})
+		if pad := (tarBlockSize - f.length%tarBlockSize) % tarBlockSize; pad > 0 {
+			segments = append(segments, newBytesSegment(make([]byte, pad)))
+		}
+	}
+	segments = append(segments, newBytesSegment(make([]byte, 2*tarBlockSize)))
+	return segments, nil
+}
+
+// archiveFiles returns files located under directory p (or all files when p is empty).
+// Archive member names are relative to the parent of p, so the directory itself
+// becomes the archive root.
+func (s *WebSeeder) archiveFiles(r *http.Request, h string, t *torrent.Torrent, p string, ra *AdaptiveReadahead) []archiveFile {
+	dir := strings.Trim(p, "/")
+	parent := ""
+	if i := strings.LastIndex(dir, "/"); i >= 0 {
+		parent = dir[:i+1]
+	}
+	var files []archiveFile
+	for _, f := range t.Files() {
+		if dir != "" && !strings.HasPrefix(f.Path(), dir+"/") {
+			continue
+		}
+		f := f
+		files = append(files, archiveFile{
+			name:   strings.TrimPrefix(f.Path(), parent),
+			path:   f.Path(),
+			length: f.Length(),
+			open: func(off int64) (io.ReadCloser, error) {
+				return s.openArchiveFile(r, h, f, off, ra)
+			},
+		})
+	}
+	return files
+}
+
+// openArchiveFile opens file content at offset, preferring completely
+// downloaded files from the file cache.
+func (s *WebSeeder) openArchiveFile(r *http.Request, h string, f *torrent.File, off int64, ra *AdaptiveReadahead) (io.ReadCloser, error) {
+	cp, err := s.fcm.Get(h, f.Path())
+	if err != nil {
+		return nil, err
+	}
+	if cp != "" {
+		file, err := os.Open(cp)
+		if err != nil {
+			return nil, err
+		}
+		_, err = file.Seek(off, io.SeekStart)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		return file, nil
+	}
+	torReader := f.NewReader()
+	torReader.SetContext(r.Context())
+	torReader.SetResponsive()
+	torReader.SetReadaheadFunc(ra.Func())
+	_, err = torReader.Seek(off, io.SeekStart)
+	if err != nil {
+		_ = torReader.Close()
+		return nil, err
+	}
+	return torReader, nil
+}
+
+func (s *WebSeeder) serveArchive(w http.ResponseWriter, r *http.Request, h string, p string, format string) {
+	logWithField := log.WithFields(log.Fields{
+		"hash":    h,
+		"path":    r.URL.Path,
+		"archive": format,
+		"range":   r.Header.Get("Range"),
+	})
+	contentType, ok := archiveContentTypes[format]
+	if !ok {
+		http.Error(w, "unsupported archive format", http.StatusBadRequest)
+		return
+	}
+	_, err := s.tom.Touch(h)
+	if err != nil {
+		log.Error(err)
+	}
+	t, release, err := s.tm.Open(r.Context(), h)
+	if err != nil {
+		logWithField.WithError(err).Error("failed to get torrent")
+		s.renderTorrentError(w, err)
+		return
+	}
+	defer release()
+	if t == nil {
+		http.NotFound(w, r)
+		return
+	}
+	ra := NewAdaptiveReadahead(t, s.cfg.MinReadahead, s.cfg.MaxReadahead)
+	files := s.archiveFiles(r, h, t, p, ra)
+	if len(files) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	name := path.Base(strings.Trim(p, "/"))
+	if strings.Trim(p, "/") == "" {
+		name = t.Name()
+	}
+	name += "." + format
+
+	var segments []archiveSegment
+	if format == ArchiveTar {
+		segments, err = tarSegments(files)
+		if err != nil {
+			logWithField.WithError(err).Error("failed to build tar archive")
+			http.Error(w, "failed to build archive", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		segments = zipSegments(h, files, s.crcs)
+	}
+	ar := newArchiveReader(segments)
+	defer func() {
+		_ = ar.Close()
+	}()
+
+	logWithField.Info("serve archive")
+	lastMod := time.Unix(0, 0)
+	tw := NewTouchWriter(w, s.tm, h).WithReadahead(ra)
+	tw.Header().Set("Content-Type", contentType)
+	tw.Header().Set("Content-Disposition", "attachment; filename=\""+name+"\"")
+	tw.Header().Set("Last-Modified", lastMod.Format(http.TimeFormat))
+	tw.Header().Set("Etag", fmt.Sprintf("\"%x\"", sha1.Sum([]byte(h+p+format))))
+	http.ServeContent(tw, r, name, lastMod, ar)
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func makeArchiveFiles(contents map[string][]byte, order []string) []archiveFile {
+	var files []archiveFile
+	for _, name := range order {
+		b := contents[name]
+		files = append(files, archiveFile{
+			name:   name,
+			path:   name,
+			length: int64(len(b)),
+			open: func(off int64) (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(b[off:])), nil
+			},
+		})
+	}
+	return files
+}
+
+func testArchiveContents() (map[string][]byte, []string) {
+	order := []string{"dir/a.txt", "dir/empty", "dir/sub/b.bin"}
+	contents := map[string][]byte{
+		"dir/a.txt":     []byte("hello world"),
+		"dir/empty":     {},
+		"dir/sub/b.bin": bytes.Repeat([]byte{1, 2, 3, 4, 5}, 10000),
+	}
+	return contents, order
+}
+
+func TestZipSegments_ValidArchive(t *testing.T) {
+	contents, order := testArchiveContents()
+	ar := newArchiveReader(zipSegments("hash", makeArchiveFiles(contents, order), newCRCCache(crcCacheCapacity, nil)))
+	data, err := io.ReadAll(ar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(data)) != ar.size {
+		t.Fatalf("expected %d bytes, got %d", ar.size, len(data))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != len(order) {
+		t.Fatalf("expected %d files, got %d", len(order), len(zr.File))
+	}
+	for i, f := range zr.File {
+		if f.Name != order[i] {
+			t.Fatalf("expected name %q, got %q", order[i], f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("%v: %v", f.Name, err)
+		}
+		if !bytes.Equal(b, contents[f.Name]) {
+			t.Fatalf("content mismatch for %v", f.Name)
+		}
+	}
+}
+
+func TestZipSegments_ResumeFromOffset(t *testing.T) {
+	contents, order := testArchiveContents()
+	full, err := io.ReadAll(newArchiveReader(zipSegments("hash", makeArchiveFiles(contents, order), newCRCCache(crcCacheCapacity, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A fresh reader with an empty CRC cache must produce identical bytes from any offset,
+	// including offsets inside file content and inside the central directory.
+	for _, off := range []int64{1, 77, int64(len(full)) / 2, int64(len(full)) - 50} {
+		ar := newArchiveReader(zipSegments("hash", makeArchiveFiles(contents, order), newCRCCache(crcCacheCapacity, nil)))
+		if _, err := ar.Seek(off, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		tail, err := io.ReadAll(ar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(tail, full[off:]) {
+			t.Fatalf("bytes from offset %d differ", off)
+		}
+	}
+}
+
+func TestArchiveReader_SeekEnd(t *testing.T) {
+	ar := newArchiveReader([]archiveSegment{newBytesSegment([]byte("abc")), newBytesSegment([]byte("def"))})
+	size, err := ar.Seek(0, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 6 {
+		t.Fatalf("expected size 6, got %d", size)
+	}
+	if _, err := ar.Seek(2, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(ar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "cdef" {
+		t.Fatalf("expected cdef, got %q", b)
+	}
+}
+
+func TestTarSegments_ValidArchive(t *testing.T) {
+	contents, order := testArchiveContents()
+	longName := "dir/" + strings.Repeat("long-name-", 20) + ".txt"
+	contents[longName] = []byte("pax")
+	order = append(order, longName)
+	segments, err := tarSegments(makeArchiveFiles(contents, order))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ar := newArchiveReader(segments)
+	if ar.size%tarBlockSize != 0 {
+		t.Fatalf("expected size aligned to %d, got %d", tarBlockSize, ar.size)
+	}
+	tr := tar.NewReader(ar)
+	for _, name := range order {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name != name {
+			t.Fatalf("expected name %q, got %q", name, hdr.Name)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, contents[name]) {
+			t.Fatalf("content mismatch for %v", name)
+		}
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Fatalf("expected end of archive, got %v", err)
+	}
+}
+
+func TestTarSegments_ResumeFromOffset(t *testing.T) {
+	contents, order := testArchiveContents()
+	segments, err := tarSegments(makeArchiveFiles(contents, order))
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := io.ReadAll(newArchiveReader(segments))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Offsets inside the first member content, inside its padding and in the middle of the archive.
+	for _, off := range []int64{513, 600, int64(len(full)) / 2} {
+		ar := newArchiveReader(segments)
+		if _, err := ar.Seek(off, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		tail, err := io.ReadAll(ar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(tail, full[off:]) {
+			t.Fatalf("bytes from offset %d differ", off)
+		}
+	}
+}
+
+func TestCRCCache_LRU(t *testing.T) {
+	c := newCRCCache(2, nil)
+	c.set("h", "a", 1)
+	c.set("h", "b", 2)
+	if _, ok := c.get("h", "a"); !ok {
+		t.Fatal("expected a cached")
+	}
+	c.set("h", "c", 3)
+	if _, ok := c.get("h", "b"); ok {
+		t.Fatal("expected least recently used b dropped")
+	}
+	for k, v := range map[string]uint32{"a": 1, "c": 3} {
+		if crc, ok := c.get("h", k); !ok || crc != v {
+			t.Fatalf("expected %v=%v, got %v %v", k, v, crc, ok)
+		}
+	}
+}
+
+func TestCRCCache_Persisted(t *testing.T) {
+	dir := t.TempDir()
+	h := strings.Repeat("a", 40)
+	if err := os.MkdirAll(filepath.Join(dir, h), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	info := &metainfo.Info{PieceLength: 10, Length: 10, Name: "a", Pieces: makeDummyPieces(1)}
+	pc, err := NewPieceCompletion(filepath.Join(dir, h), info, metainfo.NewHashFromHex(h), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	store := &FileCacheMap{p: dir}
+
+	newCRCCache(crcCacheCapacity, store).set(h, "a/b.txt", 42)
+	if crc, ok := newCRCCache(crcCacheCapacity, store).get(h, "a/b.txt"); !ok || crc != 42 {
+		t.Fatalf("expected persisted crc 42, got %v %v", crc, ok)
+	}
+	if _, ok := newCRCCache(crcCacheCapacity, store).get(strings.Repeat("b", 40), "a/b.txt"); ok {
+		t.Fatal("expected no crc for unknown torrent")
+	}
+}
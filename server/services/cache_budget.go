@@ -0,0 +1,143 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// evictionCandidate is a cached piece considered for global eviction.
+type evictionCandidate struct {
+	lru        *PieceLRU
+	index      int
+	size       int64
+	lastAccess time.Time
+	protected  bool
+}
+
+// CacheBudget enforces node-wide cache budget across PieceLRUs of all open
+// torrents. Per-torrent budgets stay in place as an upper bound, CacheBudget
+// additionally evicts globally least recently used pieces when their sum
+// exceeds the global budget.
+type CacheBudget struct {
+	mu        sync.Mutex
+	enforcing sync.Mutex
+	budget    int64
+	members   map[*PieceLRU]func(index int)
+}
+
+// NewCacheBudget creates a new global budget coordinator.
+// budget=0 means unlimited (no global eviction).
+func NewCacheBudget(budget int64) *CacheBudget {
+	if budget > 0 {
+		promCacheGlobalBudget.Set(float64(budget))
+	}
+	return &CacheBudget{
+		budget:  budget,
+		members: make(map[*PieceLRU]func(index int)),
+	}
+}
+
+// Register adds torrent LRU to the coordinator. evict is called for pieces
+// selected for global eviction, it must remove the piece from the LRU.
+func (b *CacheBudget) Register(l *PieceLRU, evict func(index int)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.members[l] = evict
+}
+
+// Unregister removes torrent LRU from the coordinator, e.g. on torrent close.
+func (b *CacheBudget) Unregister(l *PieceLRU) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.members, l)
+}
+
+// Used returns cache usage in bytes summed over all registered LRUs.
+func (b *CacheBudget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.usedLocked()
+}
+
+func (b *CacheBudget) usedLocked() int64 {
+	var used int64
+	for l := range b.members {
+		used += l.Used()
+	}
+	return used
+}
+
+// Enforce evicts pieces until global usage is within budget. Pieces are
+// evicted without b.mu held, so registering torrents is not blocked by slow
+// eviction. Concurrent calls return immediately while eviction is running.
+func (b *CacheBudget) Enforce() {
+	if b.budget <= 0 {
+		return
+	}
+	if !b.enforcing.TryLock() {
+		return
+	}
+	defer b.enforcing.Unlock()
+	b.mu.Lock()
+	used := b.usedLocked()
+	if used <= b.budget {
+		b.mu.Unlock()
+		return
+	}
+	toEvict := b.computeEvictions(used)
+	b.mu.Unlock()
+	log.Infof("global cache over budget (%d > %d), evicting %d pieces", used, b.budget, len(toEvict))
+	for _, c := range toEvict {
+		if evict := b.member(c.lru); evict != nil {
+			evict(c.index)
+		}
+	}
+}
+
+// member returns evict func of registered LRU or nil if it was unregistered.
+func (b *CacheBudget) member(l *PieceLRU) func(index int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.members[l]
+}
+
+// computeEvictions selects pieces across all registered LRUs to bring usage
+// back within budget.
+//
+// Must be called with b.mu held.
+func (b *CacheBudget) computeEvictions(used int64) []evictionCandidate {
+	lrus := make([]*PieceLRU, 0, len(b.members))
+	for l := range b.members {
+		lrus = append(lrus, l)
+	}
+	return selectEvictions(lrus, used-b.budget)
+}
+
+// selectEvictions picks pieces across LRUs freeing at least need bytes, using
+// the same two-pass strategy as PieceLRU: unprotected pieces first, then
+// pieces of completed files, each pass ordered from the globally least
+// recently accessed piece.
+func selectEvictions(lrus []*PieceLRU, need int64) []evictionCandidate {
+	var candidates []evictionCandidate
+	for _, l := range lrus {
+		candidates = append(candidates, l.evictionCandidates()...)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].protected != candidates[j].protected {
+			return !candidates[i].protected
+		}
+		return candidates[i].lastAccess.Before(candidates[j].lastAccess)
+	})
+	var toEvict []evictionCandidate
+	for _, c := range candidates {
+		if need <= 0 {
+			break
+		}
+		toEvict = append(toEvict, c)
+		need -= c.size
+	}
+	return toEvict
+}
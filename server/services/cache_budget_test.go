@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func registerLRU(b *CacheBudget, l *PieceLRU, evicted *[]int) {
+	b.Register(l, func(index int) {
+		l.Remove(index)
+		*evicted = append(*evicted, index)
+	})
+}
+
+func TestCacheBudget_EvictsGloballyLeastRecentlyUsed(t *testing.T) {
+	b := NewCacheBudget(100)
+	a, c := NewPieceLRU(0), NewPieceLRU(0)
+	var evictedA, evictedC []int
+	registerLRU(b, a, &evictedA)
+	registerLRU(b, c, &evictedC)
+
+	a.Add(0, 30)
+	time.Sleep(time.Millisecond)
+	c.Add(0, 30)
+	time.Sleep(time.Millisecond)
+	a.Add(1, 30)
+	time.Sleep(time.Millisecond)
+	// Touching a/0 makes c/0 globally least recently used.
+	a.Touch(0)
+	time.Sleep(time.Millisecond)
+	c.Add(1, 30)
+
+	if b.Used() != 120 {
+		t.Fatalf("expected used 120, got %d", b.Used())
+	}
+	b.Enforce()
+	if b.Used() > 100 {
+		t.Fatalf("expected used <= 100 after enforce, got %d", b.Used())
+	}
+	if len(evictedA) != 0 || len(evictedC) != 1 || evictedC[0] != 0 {
+		t.Fatalf("expected only c/0 evicted, got a=%v c=%v", evictedA, evictedC)
+	}
+}
+
+func TestCacheBudget_ProtectedPiecesLast(t *testing.T) {
+	b := NewCacheBudget(50)
+	a, c := NewPieceLRU(0), NewPieceLRU(0)
+	a.SetProtectedFunc(func(index int) bool { return true })
+	var evictedA, evictedC []int
+	registerLRU(b, a, &evictedA)
+	registerLRU(b, c, &evictedC)
+
+	a.Add(0, 30)
+	time.Sleep(time.Millisecond)
+	c.Add(0, 30)
+
+	b.Enforce()
+	if len(evictedA) != 0 || len(evictedC) != 1 {
+		t.Fatalf("expected unprotected c/0 evicted first, got a=%v c=%v", evictedA, evictedC)
+	}
+
+	time.Sleep(time.Millisecond)
+	a.Add(1, 30)
+	b.Enforce()
+	if b.Used() > 50 {
+		t.Fatalf("expected used <= 50, got %d", b.Used())
+	}
+	if len(evictedA) != 1 || evictedA[0] != 0 {
+		t.Fatalf("expected protected a/0 evicted when unprotected pieces are not enough, got a=%v c=%v", evictedA, evictedC)
+	}
+}
+
+func TestCacheBudget_Unlimited(t *testing.T) {
+	b := NewCacheBudget(0)
+	l := NewPieceLRU(0)
+	var evicted []int
+	registerLRU(b, l, &evicted)
+	l.Add(0, 1000)
+	b.Enforce()
+	if len(evicted) != 0 {
+		t.Fatalf("expected no eviction with unlimited budget, got %v", evicted)
+	}
+}
+
+func TestCacheBudget_Unregister(t *testing.T) {
+	b := NewCacheBudget(10)
+	l := NewPieceLRU(0)
+	var evicted []int
+	registerLRU(b, l, &evicted)
+	l.Add(0, 100)
+	b.Unregister(l)
+	if b.Used() != 0 {
+		t.Fatalf("expected used 0 after unregister, got %d", b.Used())
+	}
+	b.Enforce()
+	if len(evicted) != 0 {
+		t.Fatalf("expected no eviction after unregister, got %v", evicted)
+	}
+}
+
+func TestCacheBudget_EvictsWithoutLock(t *testing.T) {
+	b := NewCacheBudget(50)
+	l := NewPieceLRU(0)
+	var usedDuringEvict []int64
+	b.Register(l, func(index int) {
+		// Would deadlock if eviction ran with b.mu held.
+		usedDuringEvict = append(usedDuringEvict, b.Used())
+		l.Remove(index)
+	})
+	l.Add(0, 40)
+	l.Add(1, 40)
+
+	b.Enforce()
+	if len(usedDuringEvict) != 1 || usedDuringEvict[0] != 80 {
+		t.Fatalf("expected single eviction at used 80, got %v", usedDuringEvict)
+	}
+	if b.Used() != 40 {
+		t.Fatalf("expected used 40, got %d", b.Used())
+	}
+}
+
+func TestCacheBudget_SmallTorrentKeepsPageCache(t *testing.T) {
+	info := &metainfo.Info{PieceLength: 100, Length: 300, Name: "test", Pieces: makeDummyPieces(3)}
+	for _, tc := range []struct {
+		name      string
+		budget    int64
+		dropPages bool
+	}{
+		{"fits per-torrent budget", 1000, false},
+		{"no per-torrent budget", 0, false},
+		{"over per-torrent budget", 100, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			global := NewCacheBudget(1000)
+			s, err := NewStorage(StorageFile, t.TempDir(), tc.budget, global, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			impl, err := s.OpenTorrent(context.Background(), info, metainfo.Hash{1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer impl.Close()
+			ts := impl.Piece(info.Piece(0)).(storagePiece).t
+			if ts.lru == nil {
+				t.Fatal("expected torrent tracked by global budget")
+			}
+			if ts.dropPages != tc.dropPages {
+				t.Fatalf("expected drop pages %v, got %v", tc.dropPages, ts.dropPages)
+			}
+		})
+	}
+}
@@ -11,6 +11,10 @@ var (
 		Name: "torrent_web_seeder_cache_budget_bytes",
 		Help: "Configured per-torrent cache budget in bytes",
 	})
+	promCacheGlobalBudget = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "torrent_web_seeder_cache_global_budget_bytes",
+		Help: "Configured node-wide cache budget in bytes",
+	})
 	promCacheEvictions = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: "torrent_web_seeder_cache_evictions_total",
 		Help: "Total number of piece evictions",
@@ -24,6 +28,7 @@ var (
 func init() {
 	prometheus.MustRegister(promCacheBytesUsed)
 	prometheus.MustRegister(promCacheBudget)
+	prometheus.MustRegister(promCacheGlobalBudget)
 	prometheus.MustRegister(promCacheEvictions)
 	prometheus.MustRegister(promCachePieceCount)
 }
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"crypto/rand"
 	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path"
@@ -16,6 +18,13 @@ const (
 	DataDirFlag = "data-dir"
 )
 
+// randomID returns random hex encoded id of jobs and webhook events.
+func randomID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 func DistributeByHash(dirs []string, hash string) (string, error) {
 	sort.Strings(dirs)
 	hex := fmt.Sprintf("%x", sha1.Sum([]byte(hash)))[0:5]
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/bytefmt"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	DiskLowWatermarkFlag  = "disk-low-watermark"
+	DiskHighWatermarkFlag = "disk-high-watermark"
+	DiskCheckIntervalFlag = "disk-check-interval"
+)
+
+var ErrLowDiskSpace = errors.New("low disk space")
+
+var (
+	promDiskFree = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "torrent_web_seeder_disk_free_bytes",
+		Help: "Free disk space available on data dir shard",
+	}, []string{"shard"})
+	promDiskLowWatermark = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "torrent_web_seeder_disk_low_watermark",
+		Help: "Whether data dir shard is below low watermark and does not admit new torrents (1) or not (0)",
+	}, []string{"shard"})
+)
+
+func init() {
+	prometheus.MustRegister(promDiskFree)
+	prometheus.MustRegister(promDiskLowWatermark)
+}
+
+func RegisterDiskMonitorFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.StringFlag{
+			Name:   DiskLowWatermarkFlag,
+			Usage:  "free disk space on data dir shard below which pieces are evicted and new torrents are not admitted (e.g. 10GB, 0 = disabled)",
+			Value:  "0",
+			EnvVar: "DISK_LOW_WATERMARK",
+		},
+		cli.StringFlag{
+			Name:   DiskHighWatermarkFlag,
+			Usage:  "free disk space on data dir shard to restore by eviction before admitting new torrents again (defaults to low watermark)",
+			Value:  "0",
+			EnvVar: "DISK_HIGH_WATERMARK",
+		},
+		cli.DurationFlag{
+			Name:   DiskCheckIntervalFlag,
+			Usage:  "free disk space check interval",
+			Value:  10 * time.Second,
+			EnvVar: "DISK_CHECK_INTERVAL",
+		},
+	)
+}
+
+type diskMember struct {
+	shard string
+	evict func(index int)
+}
+
+// DiskMonitor watches free space on every data dir shard. When it falls below
+// low watermark, cached pieces of torrents stored on the shard are evicted
+// (least recently used first) and new torrents are not admitted to the shard
+// until free space gets back above high watermark.
+type DiskMonitor struct {
+	location string
+	low      int64
+	high     int64
+	interval time.Duration
+	free     func(path string) (uint64, uint64, error)
+	mu       sync.Mutex
+	checkMu  sync.Mutex
+	members  map[*PieceLRU]diskMember
+	blocked  map[string]bool
+	closeCh  chan struct{}
+	once     sync.Once
+}
+
+// NewDiskMonitor returns nil if low watermark is not set.
+func NewDiskMonitor(c *cli.Context) (*DiskMonitor, error) {
+	low, err := parseWatermark(c.String(DiskLowWatermarkFlag))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse disk low watermark flag")
+	}
+	if low == 0 {
+		return nil, nil
+	}
+	high, err := parseWatermark(c.String(DiskHighWatermarkFlag))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse disk high watermark flag")
+	}
+	if high < low {
+		high = low
+	}
+	interval := c.Duration(DiskCheckIntervalFlag)
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &DiskMonitor{
+		location: c.String(DataDirFlag),
+		low:      low,
+		high:     high,
+		interval: interval,
+		free:     diskFree,
+		members:  map[*PieceLRU]diskMember{},
+		blocked:  map[string]bool{},
+		closeCh:  make(chan struct{}),
+	}, nil
+}
+
+func parseWatermark(v string) (int64, error) {
+	if v == "" || v == "0" {
+		return 0, nil
+	}
+	b, err := bytefmt.ToBytes(v)
+	if err != nil {
+		return 0, err
+	}
+	return int64(b), nil
+}
+
+// dataDirShards returns directories torrents are distributed to by GetDir.
+func dataDirShards(location string) ([]string, error) {
+	if !strings.HasSuffix(location, "*") {
+		return []string{filepath.Clean(location)}, nil
+	}
+	dir, lp := filepath.Split(strings.TrimSuffix(location, "*"))
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var shards []string
+	for _, f := range files {
+		if f.IsDir() && strings.HasPrefix(f.Name(), lp) {
+			shards = append(shards, filepath.Join(dir, f.Name()))
+		}
+	}
+	return shards, nil
+}
+
+// shardOf returns shard of torrent dir returned by GetDir.
+func shardOf(dir string) string {
+	return filepath.Dir(filepath.Clean(dir))
+}
+
+// Register adds torrent LRU stored in dir. evict is called for pieces
+// selected for eviction, it must remove the piece from the LRU.
+func (s *DiskMonitor) Register(dir string, l *PieceLRU, evict func(index int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.members[l] = diskMember{shard: shardOf(dir), evict: evict}
+}
+
+// Unregister removes torrent LRU, e.g. on torrent close.
+func (s *DiskMonitor) Unregister(l *PieceLRU) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.members, l)
+}
+
+// Admit returns ErrLowDiskSpace if shard torrent h goes to is below watermark.
+func (s *DiskMonitor) Admit(h string) error {
+	dir, err := GetDir(s.location, h)
+	if err != nil {
+		return err
+	}
+	shard := shardOf(dir)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.blocked[shard] {
+		return errors.Wrapf(ErrLowDiskSpace, "shard=%v", shard)
+	}
+	return nil
+}
+
+// Check updates free space of all shards and evicts pieces from shards below
+// low watermark. Pieces are evicted without s.mu held, so Admit is not blocked
+// by slow eviction.
+func (s *DiskMonitor) Check() {
+	s.checkMu.Lock()
+	defer s.checkMu.Unlock()
+	shards, err := dataDirShards(s.location)
+	if err != nil {
+		log.WithError(err).Warnf("failed to list data dir shards location=%v", s.location)
+		return
+	}
+	need := map[string]int64{}
+	s.mu.Lock()
+	for _, shard := range shards {
+		free, _, err := s.free(shard)
+		if err != nil {
+			log.WithError(err).Warnf("failed to get free disk space shard=%v", shard)
+			continue
+		}
+		promDiskFree.WithLabelValues(shard).Set(float64(free))
+		if int64(free) < s.low {
+			if !s.blocked[shard] {
+				log.Warnf("free disk space below low watermark shard=%v free=%v low=%v", shard, free, s.low)
+			}
+			s.blocked[shard] = true
+			need[shard] = s.high - int64(free)
+		} else if int64(free) >= s.high && s.blocked[shard] {
+			log.Infof("free disk space restored shard=%v free=%v high=%v", shard, free, s.high)
+			s.blocked[shard] = false
+		}
+		v := 0.0
+		if s.blocked[shard] {
+			v = 1
+		}
+		promDiskLowWatermark.WithLabelValues(shard).Set(v)
+	}
+	s.mu.Unlock()
+	for shard, n := range need {
+		s.evict(shard, n)
+	}
+}
+
+// evict selects pieces under s.mu and evicts them after it is released.
+func (s *DiskMonitor) evict(shard string, need int64) {
+	s.mu.Lock()
+	var lrus []*PieceLRU
+	for l, m := range s.members {
+		if m.shard == shard {
+			lrus = append(lrus, l)
+		}
+	}
+	toEvict := selectEvictions(lrus, need)
+	s.mu.Unlock()
+	if len(toEvict) == 0 {
+		log.Warnf("no cached pieces to evict on shard=%v", shard)
+		return
+	}
+	log.Infof("evicting %d pieces on shard=%v to free %d bytes", len(toEvict), shard, need)
+	for _, c := range toEvict {
+		s.mu.Lock()
+		m, ok := s.members[c.lru]
+		s.mu.Unlock()
+		if ok {
+			m.evict(c.index)
+		}
+	}
+}
+
+// Run checks free space periodically until Close is called.
+func (s *DiskMonitor) Run() {
+	log.Infof("monitoring free disk space location=%v low=%v high=%v", s.location, s.low, s.high)
+	s.Check()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Check()
+		case <-s.closeCh:
+			return
+		}
+	}
+}
+
+func (s *DiskMonitor) Close() {
+	s.once.Do(func() {
+		close(s.closeCh)
+	})
+}
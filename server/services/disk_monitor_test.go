@@ -0,0 +1,109 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestDataDirShards_Wildcard(t *testing.T) {
+	base := t.TempDir()
+	for _, d := range []string{"data1", "data2", "other"} {
+		if err := os.Mkdir(filepath.Join(base, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shards, err := dataDirShards(filepath.Join(base, "data*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shards) != 2 || shards[0] != filepath.Join(base, "data1") || shards[1] != filepath.Join(base, "data2") {
+		t.Fatalf("unexpected shards %v", shards)
+	}
+	dir, err := GetDir(filepath.Join(base, "data*"), "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := shardOf(dir); s != shards[0] && s != shards[1] {
+		t.Fatalf("shard %v of dir %v is not among %v", s, dir, shards)
+	}
+}
+
+func TestDiskMonitor_Watermarks(t *testing.T) {
+	base := t.TempDir()
+	var free uint64 = 50
+	m := &DiskMonitor{
+		location: base,
+		low:      100,
+		high:     200,
+		free: func(string) (uint64, uint64, error) {
+			return free, 1000, nil
+		},
+		members: map[*PieceLRU]diskMember{},
+		blocked: map[string]bool{},
+	}
+	l := NewPieceLRU(0)
+	var evicted []int
+	m.Register(filepath.Join(base, "abc"), l, func(index int) {
+		l.Remove(index)
+		evicted = append(evicted, index)
+	})
+	l.Add(0, 100)
+	l.Add(1, 100)
+	l.Add(2, 100)
+
+	m.Check()
+	// 150 bytes needed to reach high watermark, so two oldest pieces go.
+	if len(evicted) != 2 || evicted[0] != 0 || evicted[1] != 1 {
+		t.Fatalf("expected pieces 0 and 1 evicted, got %v", evicted)
+	}
+	if err := m.Admit("def"); !errors.Is(err, ErrLowDiskSpace) {
+		t.Fatalf("expected ErrLowDiskSpace, got %v", err)
+	}
+
+	// Between watermarks admission stays blocked, but nothing is evicted.
+	free = 150
+	m.Check()
+	if len(evicted) != 2 {
+		t.Fatalf("expected no more evictions, got %v", evicted)
+	}
+	if err := m.Admit("def"); !errors.Is(err, ErrLowDiskSpace) {
+		t.Fatalf("expected ErrLowDiskSpace, got %v", err)
+	}
+
+	free = 250
+	m.Check()
+	if err := m.Admit("def"); err != nil {
+		t.Fatalf("expected torrent admitted, got %v", err)
+	}
+}
+
+func TestDiskMonitor_EvictsWithoutLock(t *testing.T) {
+	base := t.TempDir()
+	m := &DiskMonitor{
+		location: base,
+		low:      100,
+		high:     100,
+		free: func(string) (uint64, uint64, error) {
+			return 50, 1000, nil
+		},
+		members: map[*PieceLRU]diskMember{},
+		blocked: map[string]bool{},
+	}
+	l := NewPieceLRU(0)
+	var admitErrs []error
+	m.Register(filepath.Join(base, "abc"), l, func(index int) {
+		// Would deadlock if eviction ran with s.mu held, as TorrentMap
+		// admits torrents while pieces are being evicted.
+		admitErrs = append(admitErrs, m.Admit("def"))
+		l.Remove(index)
+	})
+	l.Add(0, 100)
+
+	m.Check()
+	if len(admitErrs) != 1 || !errors.Is(admitErrs[0], ErrLowDiskSpace) {
+		t.Fatalf("expected ErrLowDiskSpace during eviction, got %v", admitErrs)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build linux
+
+package services
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// diskFree returns bytes available to unprivileged users and total size of
+// the filesystem holding path.
+func diskFree(path string) (free uint64, total uint64, err error) {
+	var st unix.Statfs_t
+	if err = unix.Statfs(path, &st); err != nil {
+		return
+	}
+	return st.Bavail * uint64(st.Bsize), st.Blocks * uint64(st.Bsize), nil
+}
+
+// diskUsage returns disk space actually allocated for the file, so sparse
+// files with punched holes are not overcounted.
+func diskUsage(fi os.FileInfo) int64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return st.Blocks * 512
+	}
+	return fi.Size()
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package services
+
+import (
+	"errors"
+	"os"
+)
+
+// diskFree is not implemented on non-Linux platforms, so watermark
+// monitoring is effectively disabled there.
+func diskFree(_ string) (uint64, uint64, error) {
+	return 0, 0, errors.New("disk free space check is not supported on this platform")
+}
+
+// diskUsage returns apparent file size on non-Linux platforms.
+func diskUsage(fi os.FileInfo) int64 {
+	return fi.Size()
+}
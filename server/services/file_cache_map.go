@@ -131,3 +131,56 @@ func (s *FileCacheMap) IsDirComplete(h string, dirPath string, expectedFiles int
 	}
 	return completedCount >= expectedFiles, nil
 }
+
+// openDB opens .torrent.db of torrent h, it returns nil if torrent has no
+// data dir yet.
+func (s *FileCacheMap) openDB(h string) (*sqlite.Conn, error) {
+	dir, err := GetDir(s.p, h)
+	if err != nil {
+		return nil, err
+	}
+	f := dir + "/.torrent.db"
+	if _, err := os.Stat(f); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return sqlite.OpenConn(f, 0)
+}
+
+// GetCRC returns CRC32 of file stored in file_crc table.
+func (s *FileCacheMap) GetCRC(h string, path string) (crc uint32, ok bool, err error) {
+	db, err := s.openDB(h)
+	if err != nil || db == nil {
+		return 0, false, err
+	}
+	defer func(db *sqlite.Conn) {
+		_ = db.Close()
+	}(db)
+	err = sqlitex.Exec(
+		db, `select crc from file_crc where "path"=?`,
+		func(stmt *sqlite.Stmt) error {
+			crc = uint32(stmt.ColumnInt64(0))
+			ok = true
+			return nil
+		},
+		path)
+	if err != nil && strings.Contains(err.Error(), "no such table") {
+		return 0, false, nil
+	}
+	return
+}
+
+// SetCRC stores CRC32 of file in file_crc table. Torrent content never
+// changes, so stored CRC stays valid after eviction and re-download.
+func (s *FileCacheMap) SetCRC(h string, path string, crc uint32) error {
+	db, err := s.openDB(h)
+	if err != nil || db == nil {
+		return err
+	}
+	defer func(db *sqlite.Conn) {
+		_ = db.Close()
+	}(db)
+	return sqlitex.Exec(
+		db, `insert or replace into file_crc("path", crc) values(?, ?)`,
+		nil,
+		path, int64(crc))
+}
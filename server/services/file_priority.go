@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/anacrolix/torrent"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// FilePriority is download priority of a torrent file chosen by client.
+type FilePriority string
+
+const (
+	// FilePriorityDefault downloads file only when it is read or by a job.
+	FilePriorityDefault FilePriority = "default"
+	// FilePrioritySkip never downloads file unless it is read directly, jobs
+	// skip it.
+	FilePrioritySkip   FilePriority = "skip"
+	FilePriorityNormal FilePriority = "normal"
+	FilePriorityHigh   FilePriority = "high"
+)
+
+// filePrioritiesFile keeps chosen file priorities in data dir, so they survive
+// restart.
+const filePrioritiesFile = ".file-priorities.json"
+
+func ParseFilePriority(v string) (FilePriority, error) {
+	switch p := FilePriority(v); p {
+	case FilePriorityDefault, FilePrioritySkip, FilePriorityNormal, FilePriorityHigh:
+		return p, nil
+	}
+	return "", errors.Errorf("invalid file priority %q", v)
+}
+
+func (p FilePriority) piecePriority() torrent.PiecePriority {
+	switch p {
+	case FilePriorityNormal:
+		return torrent.PiecePriorityNormal
+	case FilePriorityHigh:
+		return torrent.PiecePriorityHigh
+	}
+	return torrent.PiecePriorityNone
+}
+
+// SetFilePriority sets download priority of file path of torrent h. Chosen
+// priority is kept in data dir after torrent is dropped and applied again when
+// it is re-added, until torrent data is removed by gc.
+func (s *TorrentMap) SetFilePriority(ctx context.Context, h string, path string, p FilePriority) error {
+	t, err := s.Get(ctx, h)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return errors.Wrapf(ErrTorrentNotFound, "infohash=%v", h)
+	}
+	f := findFile(t, path)
+	if f == nil {
+		return errors.Wrapf(ErrFileNotFound, "infohash=%v path=%v", h, path)
+	}
+	s.mux.Lock()
+	if p == FilePriorityDefault {
+		delete(s.priorities[h], path)
+		if len(s.priorities[h]) == 0 {
+			delete(s.priorities, h)
+		}
+	} else {
+		if s.priorities == nil {
+			s.priorities = map[string]map[string]FilePriority{}
+		}
+		if s.priorities[h] == nil {
+			s.priorities[h] = map[string]FilePriority{}
+		}
+		s.priorities[h][path] = p
+	}
+	s.mux.Unlock()
+	if err := s.saveFilePriorities(); err != nil {
+		log.WithError(err).Warn("failed to save file priorities")
+	}
+	f.SetPriority(p.piecePriority())
+	log.Infof("file priority set infohash=%v path=%v priority=%v", h, path, p)
+	return nil
+}
+
+// FilePriority returns download priority of file path of torrent h.
+func (s *TorrentMap) FilePriority(h string, path string) FilePriority {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if p, ok := s.priorities[h][path]; ok {
+		return p
+	}
+	return FilePriorityDefault
+}
+
+// applyFilePriorities sets chosen file priorities of re-added torrent once
+// its info is available.
+func (s *TorrentMap) applyFilePriorities(h string, t *torrent.Torrent) {
+	select {
+	case <-t.GotInfo():
+	case <-t.Closed():
+		return
+	}
+	s.mux.Lock()
+	priorities := make(map[string]FilePriority, len(s.priorities[h]))
+	for path, p := range s.priorities[h] {
+		priorities[path] = p
+	}
+	s.mux.Unlock()
+	for path, p := range priorities {
+		if f := findFile(t, path); f != nil {
+			f.SetPriority(p.piecePriority())
+		}
+	}
+}
+
+// forgetFilePriorities removes chosen file priorities of torrent h, called
+// once its data is removed.
+func (s *TorrentMap) forgetFilePriorities(h string) {
+	s.mux.Lock()
+	_, ok := s.priorities[h]
+	delete(s.priorities, h)
+	s.mux.Unlock()
+	if !ok {
+		return
+	}
+	if err := s.saveFilePriorities(); err != nil {
+		log.WithError(err).Warn("failed to save file priorities")
+	}
+}
+
+func (s *TorrentMap) filePrioritiesPath() string {
+	if s.dataDir == "" {
+		return ""
+	}
+	return filepath.Join(dataDirRoot(s.dataDir), filePrioritiesFile)
+}
+
+// saveFilePriorities writes chosen file priorities to data dir.
+func (s *TorrentMap) saveFilePriorities() error {
+	path := s.filePrioritiesPath()
+	if path == "" {
+		return nil
+	}
+	s.saveMux.Lock()
+	defer s.saveMux.Unlock()
+	s.mux.Lock()
+	b, err := json.Marshal(s.priorities)
+	s.mux.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+// loadFilePriorities reads chosen file priorities from data dir. Priorities of
+// torrents without data dir are skipped, their data was removed while
+// TorrentMap was not running.
+func (s *TorrentMap) loadFilePriorities() error {
+	path := s.filePrioritiesPath()
+	if path == "" {
+		return nil
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	priorities := map[string]map[string]FilePriority{}
+	err = json.Unmarshal(b, &priorities)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse file priorities path=%v", path)
+	}
+	for h := range priorities {
+		dir, err := GetDir(s.dataDir, h)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			delete(priorities, h)
+		}
+	}
+	s.mux.Lock()
+	s.priorities = priorities
+	s.mux.Unlock()
+	return nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anacrolix/torrent"
+	pb "github.com/webtor-io/torrent-web-seeder/proto"
+)
+
+func TestParseFilePriority(t *testing.T) {
+	expected := map[string]torrent.PiecePriority{
+		"default": torrent.PiecePriorityNone,
+		"skip":    torrent.PiecePriorityNone,
+		"normal":  torrent.PiecePriorityNormal,
+		"high":    torrent.PiecePriorityHigh,
+	}
+	for v, pp := range expected {
+		p, err := ParseFilePriority(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.piecePriority() != pp {
+			t.Fatalf("expected piece priority %v for %v, got %v", pp, v, p.piecePriority())
+		}
+		rp, err := filePriorityOf(filePriorityReply(p))
+		if err != nil || rp != p {
+			t.Fatalf("expected %v after grpc round trip, got %v %v", p, rp, err)
+		}
+	}
+	if _, err := ParseFilePriority("now"); err == nil {
+		t.Fatal("expected invalid priority error")
+	}
+	if _, err := filePriorityOf(pb.File_Priority(42)); err == nil {
+		t.Fatal("expected invalid grpc priority error")
+	}
+}
+
+func TestTorrentMap_FilePriority(t *testing.T) {
+	tm := &TorrentMap{
+		priorities: map[string]map[string]FilePriority{
+			"aa": {"t/sample.mkv": FilePrioritySkip},
+		},
+	}
+	if p := tm.FilePriority("aa", "t/sample.mkv"); p != FilePrioritySkip {
+		t.Fatalf("expected skip, got %v", p)
+	}
+	if p := tm.FilePriority("aa", "t/movie.mkv"); p != FilePriorityDefault {
+		t.Fatalf("expected default, got %v", p)
+	}
+	if p := tm.FilePriority("bb", "t/sample.mkv"); p != FilePriorityDefault {
+		t.Fatalf("expected default, got %v", p)
+	}
+}
+
+func TestTorrentMap_PersistsFilePriorities(t *testing.T) {
+	dir := t.TempDir()
+	kept, removed := strings.Repeat("a", 40), strings.Repeat("b", 40)
+	tm := &TorrentMap{
+		dataDir: dir,
+		priorities: map[string]map[string]FilePriority{
+			kept:    {"t/sample.mkv": FilePrioritySkip},
+			removed: {"t/sample.mkv": FilePriorityHigh},
+		},
+	}
+	if err := tm.saveFilePriorities(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, kept), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Data of removed torrent is gone since previous run.
+	r := &TorrentMap{dataDir: dir}
+	if err := r.loadFilePriorities(); err != nil {
+		t.Fatal(err)
+	}
+	if p := r.FilePriority(kept, "t/sample.mkv"); p != FilePrioritySkip {
+		t.Fatalf("expected skip restored, got %v", p)
+	}
+	if _, ok := r.priorities[removed]; ok {
+		t.Fatal("expected priorities of torrent without data dropped")
+	}
+
+	r.forgetFilePriorities(kept)
+	r = &TorrentMap{dataDir: dir}
+	if err := r.loadFilePriorities(); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.priorities) != 0 {
+		t.Fatalf("expected no priorities after forget, got %v", r.priorities)
+	}
+}
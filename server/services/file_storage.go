@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// fileData accesses content files of a torrent with plain pread/pwrite calls.
+// Unlike mmapData it does not map files into address space, so it has no
+// limit on torrent size on 32-bit systems and does not account file pages
+// as process memory.
+type fileData struct {
+	files    []*os.File
+	fileLens []int64
+	length   int64
+}
+
+func openFileData(info *metainfo.Info, dir string) (_ torrentData, err error) {
+	d := &fileData{}
+	defer func() {
+		if err != nil {
+			_ = d.Close()
+		}
+	}()
+	for _, miFile := range info.UpvertedFiles() {
+		var fileName string
+		fileName, err = contentFilePath(dir, info, miFile)
+		if err != nil {
+			return
+		}
+		var f *os.File
+		f, err = openContentFile(fileName, miFile.Length)
+		if err != nil {
+			err = fmt.Errorf("file %q: %w", miFile.DisplayPath(info), err)
+			return
+		}
+		d.files = append(d.files, f)
+		d.fileLens = append(d.fileLens, miFile.Length)
+		d.length += miFile.Length
+	}
+	return d, nil
+}
+
+// ReadAt reads from files covered by the range. It returns io.EOF if the range
+// ends past the end of the torrent.
+func (d *fileData) ReadAt(b []byte, off int64) (n int, err error) {
+	return d.rw(b, off, func(f *os.File, p []byte, off int64) (int, error) {
+		n, err := f.ReadAt(p, off)
+		if errors.Is(err, io.EOF) && n == len(p) {
+			err = nil
+		}
+		return n, err
+	})
+}
+
+// WriteAt writes to files covered by the range. It returns io.EOF if the range
+// ends past the end of the torrent.
+func (d *fileData) WriteAt(b []byte, off int64) (n int, err error) {
+	return d.rw(b, off, func(f *os.File, p []byte, off int64) (int, error) {
+		return f.WriteAt(p, off)
+	})
+}
+
+func (d *fileData) rw(b []byte, off int64, op func(f *os.File, p []byte, off int64) (int, error)) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= d.length {
+		return 0, io.EOF
+	}
+	for _, r := range spanFileRegions(d.fileLens, off, min(int64(len(b)), d.length-off)) {
+		var rn int
+		rn, err = op(d.files[r.fileIndex], b[n:n+int(r.length)], r.offset)
+		n += rn
+		if err != nil {
+			return
+		}
+	}
+	if n < len(b) {
+		err = io.EOF
+	}
+	return
+}
+
+// Flush does nothing, written data is already in page cache and is written
+// back by the kernel.
+func (d *fileData) Flush() error {
+	return nil
+}
+
+func (d *fileData) Files() []*os.File {
+	return d.files
+}
+
+// DropPages advises the kernel to drop cached pages of a byte range in the
+// concatenated files with POSIX_FADV_DONTNEED.
+func (d *fileData) DropPages(off int64, length int64) {
+	for _, r := range spanFileRegions(d.fileLens, off, length) {
+		_ = fadviseEvict(d.files[r.fileIndex], r.offset, r.length)
+	}
+}
+
+func (d *fileData) Close() (err error) {
+	for _, f := range d.files {
+		err = errors.Join(err, f.Close())
+	}
+	d.files = nil
+	return
+}
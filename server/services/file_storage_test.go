@@ -0,0 +1,70 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestFileData_ReadWriteAcrossFiles(t *testing.T) {
+	info := &metainfo.Info{
+		PieceLength: 100,
+		Name:        "test",
+		Files: []metainfo.FileInfo{
+			{Path: []string{"a"}, Length: 150},
+			{Path: []string{"empty"}, Length: 0},
+			{Path: []string{"b"}, Length: 200},
+		},
+		Pieces: makeDummyPieces(4),
+	}
+	data, err := openFileData(info, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+
+	// Piece 1 spans files "a" and "b".
+	in := bytes.Repeat([]byte{7}, 100)
+	if n, err := data.WriteAt(in, 100); err != nil || n != 100 {
+		t.Fatalf("unexpected write n=%v err=%v", n, err)
+	}
+	out := make([]byte, 100)
+	if n, err := data.ReadAt(out, 100); err != nil || n != 100 {
+		t.Fatalf("unexpected read n=%v err=%v", n, err)
+	}
+	if !bytes.Equal(in, out) {
+		t.Fatal("read data differs from written")
+	}
+
+	// Read past the end of the last file.
+	n, err := data.ReadAt(out, 300)
+	if n != 50 || err != io.EOF {
+		t.Fatalf("expected 50 bytes and EOF, got n=%v err=%v", n, err)
+	}
+	if _, err := data.ReadAt(out, 350); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+
+	// Eviction punches holes, so evicted range reads back as zeros.
+	files := data.Files()
+	for _, r := range spanFileRegions([]int64{150, 0, 200}, 100, 100) {
+		if err := punchHole(files[r.fileIndex], r.offset, r.length); err != nil {
+			t.Skipf("punch hole is not supported: %v", err)
+		}
+	}
+	data.DropPages(100, 100)
+	if _, err := data.ReadAt(out, 100); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, make([]byte, 100)) {
+		t.Fatal("expected zeros after eviction")
+	}
+}
+
+func TestNewStorage_UnknownKind(t *testing.T) {
+	if _, err := NewStorage("tmpfs", t.TempDir(), 0, nil, nil, nil); err == nil {
+		t.Fatal("expected unknown storage error")
+	}
+}
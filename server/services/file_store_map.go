@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/anacrolix/torrent/metainfo"
 	log "github.com/sirupsen/logrus"
@@ -13,7 +14,8 @@ import (
 )
 
 const (
-	InputFlag string = "input"
+	InputFlag               string = "input"
+	InputRescanIntervalFlag        = "input-rescan-interval"
 )
 
 func RegisterFileStoreFlags(f []cli.Flag) []cli.Flag {
@@ -23,23 +25,47 @@ func RegisterFileStoreFlags(f []cli.Flag) []cli.Flag {
 			Usage:  "torrent file path",
 			EnvVar: "INPUT",
 		},
+		cli.DurationFlag{
+			Name:   InputRescanIntervalFlag,
+			Usage:  "interval of input directory rescan, 0 loads it only once",
+			Value:  10 * time.Second,
+			EnvVar: "INPUT_RESCAN_INTERVAL",
+		},
 	)
 }
 
+// inputFile is a loaded torrent file, kept to skip parsing of unchanged files.
+// mi is nil if file failed to parse.
+type inputFile struct {
+	modTime time.Time
+	size    int64
+	mi      *metainfo.MetaInfo
+}
+
+// FileStoreMap serves torrents from input path. Input is scanned on first
+// access and rescanned in background, so lookups never wait for a scan.
 type FileStoreMap struct {
-	p     string
-	once  sync.Once
-	infos map[string]*metainfo.MetaInfo
-	err   error
+	p        string
+	interval time.Duration
+	once     sync.Once
+	scanMux  sync.Mutex // serializes scans, guards files
+	files    map[string]*inputFile
+	mux      sync.Mutex
+	infos    map[string]*metainfo.MetaInfo
+	err      error
+	closeCh  chan struct{}
 }
 
 func NewFileStoreMap(c *cli.Context) *FileStoreMap {
 	return &FileStoreMap{
-		p: c.String(InputFlag),
+		p:        c.String(InputFlag),
+		interval: c.Duration(InputRescanIntervalFlag),
+		files:    map[string]*inputFile{},
+		closeCh:  make(chan struct{}),
 	}
 }
 
-func (s *FileStoreMap) loadFiles() (map[string]*metainfo.MetaInfo, error) {
+func (s *FileStoreMap) inputPath() string {
 	path := s.p
 	usr, _ := user.Current()
 	dir := usr.HomeDir
@@ -48,6 +74,25 @@ func (s *FileStoreMap) loadFiles() (map[string]*metainfo.MetaInfo, error) {
 	} else if strings.HasPrefix(path, "~/") {
 		path = filepath.Join(dir, path[2:])
 	}
+	return path
+}
+
+// loadFile parses torrent file unless it is unchanged since previous scan.
+func (s *FileStoreMap) loadFile(path string, fi os.FileInfo) (*inputFile, error) {
+	if f, ok := s.files[path]; ok && f.modTime.Equal(fi.ModTime()) && f.size == fi.Size() {
+		return f, nil
+	}
+	mi, err := metainfo.LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("loaded torrent path=%v infohash=%v", path, mi.HashInfoBytes().HexString())
+	return &inputFile{modTime: fi.ModTime(), size: fi.Size(), mi: mi}, nil
+}
+
+// loadFiles scans input path. Must be called with s.scanMux held.
+func (s *FileStoreMap) loadFiles() (map[string]*metainfo.MetaInfo, error) {
+	path := s.inputPath()
 	m := map[string]*metainfo.MetaInfo{}
 	if path == "" {
 		return m, nil
@@ -56,51 +101,113 @@ func (s *FileStoreMap) loadFiles() (map[string]*metainfo.MetaInfo, error) {
 	if err != nil {
 		return nil, err
 	}
+	files := map[string]*inputFile{}
 	if fi.IsDir() {
 		fs, err := os.ReadDir(path)
 		if err != nil {
 			return nil, err
 		}
 		for _, f := range fs {
-			if !f.IsDir() && strings.HasSuffix(f.Name(), ".torrent") {
-				mi, err := metainfo.LoadFromFile(path + "/" + f.Name())
-				if err != nil {
-					log.WithError(err).Error("failed to load torrent")
-					continue
-				}
-				m[mi.HashInfoBytes().HexString()] = mi
+			if f.IsDir() || !strings.HasSuffix(f.Name(), ".torrent") {
+				continue
 			}
+			fp := path + "/" + f.Name()
+			ffi, err := f.Info()
+			if err != nil {
+				// File was removed during scan.
+				continue
+			}
+			inf, err := s.loadFile(fp, ffi)
+			if err != nil {
+				log.WithError(err).Errorf("failed to load torrent path=%v", fp)
+				// Remember broken file, so it is not parsed again until it changes.
+				inf = &inputFile{modTime: ffi.ModTime(), size: ffi.Size()}
+			}
+			files[fp] = inf
 		}
 	} else {
-		mi, err := metainfo.LoadFromFile(path)
+		inf, err := s.loadFile(path, fi)
 		if err != nil {
 			return nil, err
 		}
-		m[mi.HashInfoBytes().HexString()] = mi
+		files[path] = inf
+	}
+	for fp := range s.files {
+		if _, ok := files[fp]; !ok {
+			log.Infof("removed torrent path=%v", fp)
+		}
+	}
+	s.files = files
+	for _, f := range files {
+		if f.mi != nil {
+			m[f.mi.HashInfoBytes().HexString()] = f.mi
+		}
 	}
 	return m, nil
 }
 
+// rescan scans input and swaps loaded torrents. Previously loaded torrents
+// are kept if scan fails.
+func (s *FileStoreMap) rescan() {
+	s.scanMux.Lock()
+	defer s.scanMux.Unlock()
+	infos, err := s.loadFiles()
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err != nil && s.infos != nil {
+		log.WithError(err).Warn("failed to rescan input, keeping previously loaded torrents")
+		return
+	}
+	s.infos, s.err = infos, err
+}
+
+// load returns torrents loaded by the latest successful scan.
+func (s *FileStoreMap) load() (map[string]*metainfo.MetaInfo, error) {
+	s.once.Do(s.rescan)
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.infos, s.err
+}
+
+func (s *FileStoreMap) Serve() error {
+	s.once.Do(s.rescan)
+	if s.p == "" || s.interval <= 0 {
+		<-s.closeCh
+		return nil
+	}
+	log.Infof("serving FileStoreMap input=%v interval=%v", s.p, s.interval)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.rescan()
+		case <-s.closeCh:
+			return nil
+		}
+	}
+}
+
+func (s *FileStoreMap) Close() {
+	close(s.closeCh)
+}
+
 func (s *FileStoreMap) Get(h string) (*metainfo.MetaInfo, error) {
-	s.once.Do(func() {
-		s.infos, s.err = s.loadFiles()
-	})
-	if s.err != nil {
-		return nil, s.err
+	infos, err := s.load()
+	if err != nil {
+		return nil, err
 	}
-	i := s.infos[h]
+	i := infos[h]
 	return i, nil
 }
 
 func (s *FileStoreMap) List() ([]string, error) {
 	hs := []string{}
-	s.once.Do(func() {
-		s.infos, s.err = s.loadFiles()
-	})
-	if s.err != nil {
-		return hs, s.err
+	infos, err := s.load()
+	if err != nil {
+		return hs, err
 	}
-	for h := range s.infos {
+	for h := range infos {
 		hs = append(hs, h)
 	}
 	return hs, nil
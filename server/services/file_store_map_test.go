@@ -0,0 +1,88 @@
+package services
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestFileStoreMap_Rescan(t *testing.T) {
+	dir := t.TempDir()
+	b, err := os.ReadFile("../../torrents/Sintel.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mi, err := metainfo.Load(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mi.HashInfoBytes().HexString()
+	s := &FileStoreMap{p: dir, files: map[string]*inputFile{}}
+
+	l, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 0 {
+		t.Fatalf("expected empty input, got %v", l)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "sintel.torrent"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.torrent"), []byte("not bencode"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Get(h); got != nil {
+		t.Fatal("expected added torrent loaded only by rescan")
+	}
+	s.rescan()
+	got, err := s.Get(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Fatal("expected added torrent to be loaded")
+	}
+	l, err = s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 || l[0] != h {
+		t.Fatalf("expected only %v listed, got %v", h, l)
+	}
+
+	moved := dir + ".moved"
+	if err := os.Rename(dir, moved); err != nil {
+		t.Fatal(err)
+	}
+	s.rescan()
+	if got, err := s.Get(h); err != nil || got == nil {
+		t.Fatalf("expected previously loaded torrent kept on failed scan, got %v %v", got, err)
+	}
+	if err := os.Rename(moved, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "sintel.torrent")); err != nil {
+		t.Fatal(err)
+	}
+	s.rescan()
+	got, err = s.Get(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Fatal("expected removed torrent to be unloaded")
+	}
+}
+
+func TestFileStoreMap_FailedInitialScan(t *testing.T) {
+	s := &FileStoreMap{p: filepath.Join(t.TempDir(), "missing"), files: map[string]*inputFile{}}
+	if _, err := s.Get("a"); err == nil {
+		t.Fatal("expected error of missing input")
+	}
+}
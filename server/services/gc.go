@@ -0,0 +1,271 @@
+package services
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	GCIntervalFlag  = "gc-interval"
+	GCRetentionFlag = "gc-retention"
+	touchSuffix     = ".touch"
+	tombstoneSuffix = ".gc"
+)
+
+var (
+	promGCRemovedDirs = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "torrent_web_seeder_gc_removed_dirs_total",
+		Help: "Total number of stale torrent data directories removed by gc",
+	})
+	promGCReclaimedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "torrent_web_seeder_gc_reclaimed_bytes_total",
+		Help: "Total number of bytes reclaimed by gc",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(promGCRemovedDirs)
+	prometheus.MustRegister(promGCReclaimedBytes)
+}
+
+func RegisterGCFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.DurationFlag{
+			Name:   GCIntervalFlag,
+			Usage:  "interval of stale torrent data removal, 0 disables background gc",
+			Value:  0,
+			EnvVar: "GC_INTERVAL",
+		},
+		cli.DurationFlag{
+			Name:   GCRetentionFlag,
+			Usage:  "torrent data not touched for longer than retention period is removed by gc",
+			Value:  7 * 24 * time.Hour,
+			EnvVar: "GC_RETENTION",
+		},
+	)
+}
+
+// GCItem is a stale torrent data directory found by gc.
+type GCItem struct {
+	InfoHash string    `json:"info_hash"`
+	Dir      string    `json:"dir"`
+	Touched  time.Time `json:"touched"`
+	Bytes    int64     `json:"bytes"`
+}
+
+// GCReport summarizes single gc run.
+type GCReport struct {
+	Items []GCItem `json:"items"`
+	Bytes int64    `json:"bytes"`
+}
+
+// GC removes torrent data directories whose .touch file is older than
+// retention period and which are not open in TorrentMap, together with their
+// .touch file and cached magnet metainfo.
+type GC struct {
+	location  string
+	retention time.Duration
+	interval  time.Duration
+	tm        *TorrentMap
+	closeCh   chan struct{}
+}
+
+// NewGC returns nil if background gc is disabled.
+func NewGC(c *cli.Context, tm *TorrentMap) *GC {
+	if c.Duration(GCIntervalFlag) <= 0 {
+		return nil
+	}
+	gc := NewGCWithRetention(c.String(DataDirFlag), c.Duration(GCRetentionFlag), tm)
+	gc.interval = c.Duration(GCIntervalFlag)
+	return gc
+}
+
+// NewGCWithRetention creates gc for data dir location. tm may be nil when gc
+// runs outside of server process.
+func NewGCWithRetention(location string, retention time.Duration, tm *TorrentMap) *GC {
+	return &GC{
+		location:  location,
+		retention: retention,
+		tm:        tm,
+		closeCh:   make(chan struct{}),
+	}
+}
+
+// Collect removes stale torrent data directories. With dryRun set it only
+// reports what would be removed.
+func (s *GC) Collect(dryRun bool) (*GCReport, error) {
+	shards, err := dataDirShards(s.location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list data dir shards location=%v", s.location)
+	}
+	report := &GCReport{}
+	deadline := time.Now().Add(-s.retention)
+	for _, shard := range shards {
+		if !dryRun {
+			removeTombstones(shard)
+		}
+		items, err := staleTorrentDirs(shard, deadline)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range items {
+			removed, err := s.remove(i, dryRun)
+			if err != nil {
+				log.WithError(err).Warnf("failed to remove stale torrent data dir=%v", i.Dir)
+				continue
+			}
+			if !removed {
+				continue
+			}
+			report.Items = append(report.Items, i)
+			report.Bytes += i.Bytes
+		}
+	}
+	return report, nil
+}
+
+func (s *GC) remove(i GCItem, dryRun bool) (bool, error) {
+	if dryRun {
+		return s.tm == nil || !s.tm.IsActive(i.InfoHash), nil
+	}
+	// Data dir is only renamed to tombstone while TorrentMap is locked, so
+	// slow removal of its content does not block torrent requests.
+	tombstone := i.Dir + tombstoneSuffix
+	detach := func() error {
+		err := os.Rename(i.Dir, tombstone)
+		if err != nil {
+			return err
+		}
+		for _, f := range []string{i.Dir + touchSuffix, i.Dir + magnetTorrentSuffix} {
+			err = os.Remove(f)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	}
+	removed := true
+	var err error
+	if s.tm == nil {
+		err = detach()
+	} else {
+		removed, err = s.tm.IfInactive(i.InfoHash, detach)
+	}
+	if !removed || err != nil {
+		return removed, err
+	}
+	if s.tm != nil {
+		s.tm.forgetFilePriorities(i.InfoHash)
+	}
+	return true, os.RemoveAll(tombstone)
+}
+
+// removeTombstones removes data dirs left by gc interrupted during removal.
+func removeTombstones(shard string) {
+	entries, err := os.ReadDir(shard)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		h, ok := strings.CutSuffix(e.Name(), tombstoneSuffix)
+		if !ok || !e.IsDir() || !infoHashR.MatchString(h) {
+			continue
+		}
+		err = os.RemoveAll(filepath.Join(shard, e.Name()))
+		if err != nil {
+			log.WithError(err).Warnf("failed to remove gc tombstone=%v", e.Name())
+		}
+	}
+}
+
+// staleTorrentDirs lists torrent data dirs of shard touched before deadline.
+func staleTorrentDirs(shard string, deadline time.Time) ([]GCItem, error) {
+	entries, err := os.ReadDir(shard)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read shard=%v", shard)
+	}
+	var items []GCItem
+	for _, e := range entries {
+		h, ok := strings.CutSuffix(e.Name(), touchSuffix)
+		if !ok || e.IsDir() || !infoHashR.MatchString(h) {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if !fi.ModTime().Before(deadline) {
+			continue
+		}
+		dir := filepath.Join(shard, h)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		bytes := dirDiskUsage(dir)
+		if mfi, err := os.Stat(dir + magnetTorrentSuffix); err == nil {
+			bytes += diskUsage(mfi)
+		}
+		items = append(items, GCItem{
+			InfoHash: h,
+			Dir:      dir,
+			Touched:  fi.ModTime(),
+			Bytes:    bytes,
+		})
+	}
+	return items, nil
+}
+
+func dirDiskUsage(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		size += diskUsage(fi)
+		return nil
+	})
+	return size
+}
+
+func (s *GC) collect() {
+	report, err := s.Collect(false)
+	if err != nil {
+		log.WithError(err).Error("failed to collect stale torrent data")
+		return
+	}
+	promGCRemovedDirs.Add(float64(len(report.Items)))
+	promGCReclaimedBytes.Add(float64(report.Bytes))
+	if len(report.Items) > 0 {
+		log.Infof("gc removed %v stale torrent data dirs, reclaimed %v bytes", len(report.Items), report.Bytes)
+	}
+}
+
+func (s *GC) Serve() error {
+	log.Infof("serving GC location=%v interval=%v retention=%v", s.location, s.interval, s.retention)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.collect()
+		case <-s.closeCh:
+			return nil
+		}
+	}
+}
+
+func (s *GC) Close() {
+	close(s.closeCh)
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func makeTorrentDir(t *testing.T, shard string, h string, touched time.Time) string {
+	dir := filepath.Join(shard, h)
+	if err := os.MkdirAll(filepath.Join(dir, "content"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".torrent.db"), make([]byte, 8192), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+touchSuffix, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dir+touchSuffix, touched, touched); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestGC_Collect(t *testing.T) {
+	base := t.TempDir()
+	shard := filepath.Join(base, "data1")
+	stale := makeTorrentDir(t, shard, strings.Repeat("a", 40), time.Now().Add(-48*time.Hour))
+	fresh := makeTorrentDir(t, shard, strings.Repeat("b", 40), time.Now())
+	gc := NewGCWithRetention(filepath.Join(base, "data*"), 24*time.Hour, nil)
+
+	report, err := gc.Collect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Items) != 1 || report.Items[0].Dir != stale || report.Bytes <= 0 {
+		t.Fatalf("unexpected dry run report %+v", report)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Fatalf("expected stale dir kept on dry run, got %v", err)
+	}
+
+	report, err = gc.Collect(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Items) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("expected stale dir removed, got %v", err)
+	}
+	if _, err := os.Stat(stale + touchSuffix); !os.IsNotExist(err) {
+		t.Fatalf("expected stale touch file removed, got %v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Fatalf("expected fresh dir kept, got %v", err)
+	}
+}
+
+func TestGC_SkipsActiveTorrents(t *testing.T) {
+	base := t.TempDir()
+	h := strings.Repeat("a", 40)
+	stale := makeTorrentDir(t, base, h, time.Now().Add(-48*time.Hour))
+	tm := &TorrentMap{entries: map[string]*torrentEntry{h: {}}}
+	gc := NewGCWithRetention(base, 24*time.Hour, tm)
+
+	report, err := gc.Collect(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Items) != 0 {
+		t.Fatalf("expected active torrent skipped, got %+v", report)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Fatalf("expected active dir kept, got %v", err)
+	}
+}
+
+func TestGC_RemovesMagnetMetainfoAndTombstones(t *testing.T) {
+	base := t.TempDir()
+	h := strings.Repeat("a", 40)
+	stale := makeTorrentDir(t, base, h, time.Now().Add(-48*time.Hour))
+	if err := os.WriteFile(stale+magnetTorrentSuffix, make([]byte, 8192), 0644); err != nil {
+		t.Fatal(err)
+	}
+	orphan := filepath.Join(base, strings.Repeat("b", 40)+tombstoneSuffix)
+	if err := os.MkdirAll(filepath.Join(orphan, "content"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tm := &TorrentMap{
+		entries:    map[string]*torrentEntry{},
+		priorities: map[string]map[string]FilePriority{h: {"t/a": FilePrioritySkip}},
+	}
+	gc := NewGCWithRetention(base, 24*time.Hour, tm)
+
+	dry, err := gc.Collect(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := gc.Collect(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Items) != 1 || report.Bytes != dry.Bytes || report.Bytes < 2*8192 {
+		t.Fatalf("unexpected report %+v dry run %+v", report, dry)
+	}
+	if p := tm.FilePriority(h, "t/a"); p != FilePriorityDefault {
+		t.Fatalf("expected file priorities of removed torrent forgotten, got %v", p)
+	}
+	for _, p := range []string{stale, stale + magnetTorrentSuffix, stale + tombstoneSuffix, orphan} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Fatalf("expected %v removed, got %v", p, err)
+		}
+	}
+}
+
+func TestGC_SkipsForeignFiles(t *testing.T) {
+	base := t.TempDir()
+	// Default data dir is os.TempDir(), shared with other programs.
+	foreign := makeTorrentDir(t, base, "session", time.Now().Add(-48*time.Hour))
+	backup := filepath.Join(base, "backup"+tombstoneSuffix)
+	if err := os.MkdirAll(backup, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gc := NewGCWithRetention(base, 24*time.Hour, nil)
+
+	report, err := gc.Collect(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Items) != 0 {
+		t.Fatalf("expected foreign files skipped, got %+v", report)
+	}
+	for _, p := range []string{foreign, foreign + touchSuffix, backup} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("expected %v kept, got %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,533 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	sqlite "github.com/go-llsqlite/adapter"
+	"github.com/go-llsqlite/adapter/sqlitex"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	JobConcurrencyFlag = "job-concurrency"
+	jobsStateFile      = ".jobs.json"
+	jobPollInterval    = 5 * time.Second
+	jobKeepAlive       = time.Minute
+	jobsKeepFinished   = 1000
+
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobFinished = errors.New("job already finished")
+)
+
+var promJobs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "torrent_web_seeder_jobs",
+	Help: "Number of download jobs by state",
+}, []string{"state"})
+
+func init() {
+	prometheus.MustRegister(promJobs)
+}
+
+func RegisterJobFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.IntFlag{
+			Name:   JobConcurrencyFlag,
+			Usage:  "number of download jobs running concurrently",
+			Value:  2,
+			EnvVar: "JOB_CONCURRENCY",
+		},
+	)
+}
+
+// Job downloads whole torrent, or a file or directory of it when Path is
+// set, in background.
+type Job struct {
+	ID             string    `json:"id"`
+	InfoHash       string    `json:"info_hash"`
+	Path           string    `json:"path,omitempty"`
+	Priority       int       `json:"priority"`
+	State          string    `json:"state"`
+	Error          string    `json:"error,omitempty"`
+	Files          int       `json:"files"`
+	CompletedFiles int       `json:"completed_files"`
+	Bytes          int64     `json:"bytes"`
+	CompletedBytes int64     `json:"completed_bytes"`
+	Created        time.Time `json:"created"`
+	Started        time.Time `json:"started,omitzero"`
+	Finished       time.Time `json:"finished,omitzero"`
+}
+
+func (j *Job) finished() bool {
+	return j.State == JobCompleted || j.State == JobFailed || j.State == JobCanceled
+}
+
+type jobsState struct {
+	Jobs []*Job `json:"jobs"`
+}
+
+// JobQueue runs download jobs with bounded concurrency, highest priority
+// first. Jobs are persisted in data dir, so queued and interrupted jobs are
+// resumed after restart. Completion is tracked by file_completion table of
+// torrent data dir.
+type JobQueue struct {
+	tm          *TorrentMap
+	dataDir     string
+	path        string
+	concurrency int
+	poll        time.Duration
+	mux         sync.Mutex
+	jobs        map[string]*Job
+	cancels     map[string]context.CancelFunc
+	closing     bool
+	wakeCh      chan struct{}
+	closeCh     chan struct{}
+	wg          sync.WaitGroup
+}
+
+func NewJobQueue(c *cli.Context, tm *TorrentMap) *JobQueue {
+	q := newJobQueue(c.String(DataDirFlag), c.Int(JobConcurrencyFlag), tm)
+	err := q.load()
+	if err != nil {
+		log.WithError(err).Warn("failed to load jobs state")
+	}
+	return q
+}
+
+func newJobQueue(location string, concurrency int, tm *TorrentMap) *JobQueue {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &JobQueue{
+		tm:          tm,
+		dataDir:     location,
+		path:        filepath.Join(dataDirRoot(location), jobsStateFile),
+		concurrency: concurrency,
+		poll:        jobPollInterval,
+		jobs:        map[string]*Job{},
+		cancels:     map[string]context.CancelFunc{},
+		wakeCh:      make(chan struct{}, 1),
+		closeCh:     make(chan struct{}),
+	}
+}
+
+// load restores jobs from state file. Jobs interrupted by shutdown are queued
+// again.
+func (s *JobQueue) load() error {
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	st := &jobsState{}
+	err = json.Unmarshal(b, st)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse jobs state path=%v", s.path)
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, j := range st.Jobs {
+		if j.State == JobRunning {
+			j.State = JobQueued
+		}
+		s.jobs[j.ID] = j
+	}
+	s.updateMetrics()
+	return nil
+}
+
+// save must be called with s.mux held.
+func (s *JobQueue) save() {
+	s.prune()
+	st := &jobsState{Jobs: s.sorted()}
+	b, err := json.Marshal(st)
+	if err != nil {
+		log.WithError(err).Error("failed to encode jobs state")
+		return
+	}
+	err = writeFileAtomic(s.path, b)
+	if err != nil {
+		log.WithError(err).Warnf("failed to write jobs state path=%v", s.path)
+	}
+}
+
+// prune drops oldest finished jobs above jobsKeepFinished.
+func (s *JobQueue) prune() {
+	var finished []*Job
+	for _, j := range s.jobs {
+		if j.finished() {
+			finished = append(finished, j)
+		}
+	}
+	if len(finished) <= jobsKeepFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].Finished.Before(finished[j].Finished)
+	})
+	for _, j := range finished[:len(finished)-jobsKeepFinished] {
+		delete(s.jobs, j.ID)
+	}
+}
+
+func (s *JobQueue) updateMetrics() {
+	counts := map[string]int{JobQueued: 0, JobRunning: 0, JobCompleted: 0, JobFailed: 0, JobCanceled: 0}
+	for _, j := range s.jobs {
+		counts[j.State]++
+	}
+	for state, n := range counts {
+		promJobs.WithLabelValues(state).Set(float64(n))
+	}
+}
+
+// sorted returns jobs by creation time.
+func (s *JobQueue) sorted() []*Job {
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].Created.Equal(jobs[j].Created) {
+			return jobs[i].Created.Before(jobs[j].Created)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+func (s *JobQueue) changed() {
+	s.updateMetrics()
+	s.save()
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// Submit queues download of torrent h, or of file or directory path of it.
+// Unfinished job for the same torrent and path is returned instead of a new
+// one, with priority raised if needed.
+func (s *JobQueue) Submit(h string, path string, priority int) (Job, error) {
+	h = strings.ToLower(h)
+	if !infoHashR.MatchString(h) {
+		return Job{}, errors.Errorf("invalid infohash %q", h)
+	}
+	path = strings.Trim(path, "/")
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, j := range s.jobs {
+		if j.InfoHash == h && j.Path == path && !j.finished() {
+			if priority > j.Priority {
+				j.Priority = priority
+				s.changed()
+			}
+			return *j, nil
+		}
+	}
+	j := &Job{
+		ID:       randomID(),
+		InfoHash: h,
+		Path:     path,
+		Priority: priority,
+		State:    JobQueued,
+		Created:  time.Now(),
+	}
+	s.jobs[j.ID] = j
+	log.Infof("job queued id=%v infohash=%v path=%v priority=%v", j.ID, h, path, priority)
+	s.changed()
+	return *j, nil
+}
+
+// Cancel stops queued or running job.
+func (s *JobQueue) Cancel(id string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return errors.Wrapf(ErrJobNotFound, "id=%v", id)
+	}
+	if j.finished() {
+		return errors.Wrapf(ErrJobFinished, "id=%v state=%v", id, j.State)
+	}
+	j.State = JobCanceled
+	j.Finished = time.Now()
+	if cancel, ok := s.cancels[id]; ok {
+		cancel()
+	}
+	log.Infof("job canceled id=%v", id)
+	s.changed()
+	return nil
+}
+
+func (s *JobQueue) Get(id string) (Job, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return Job{}, errors.Wrapf(ErrJobNotFound, "id=%v", id)
+	}
+	return *j, nil
+}
+
+// List returns all jobs by creation time.
+func (s *JobQueue) List() []Job {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, j := range s.sorted() {
+		jobs = append(jobs, *j)
+	}
+	return jobs
+}
+
+// next returns queued job with highest priority, oldest first. It must be
+// called with s.mux held.
+func (s *JobQueue) next() *Job {
+	var next *Job
+	for _, j := range s.sorted() {
+		if j.State != JobQueued {
+			continue
+		}
+		if next == nil || j.Priority > next.Priority {
+			next = j
+		}
+	}
+	return next
+}
+
+func (s *JobQueue) schedule() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for !s.closing && len(s.cancels) < s.concurrency {
+		j := s.next()
+		if j == nil {
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		s.cancels[j.ID] = cancel
+		j.State = JobRunning
+		j.Error = ""
+		j.Started = time.Now()
+		s.updateMetrics()
+		s.save()
+		s.wg.Add(1)
+		go func(j Job) {
+			defer s.wg.Done()
+			err := s.run(ctx, j)
+			s.finish(j.ID, err)
+		}(*j)
+	}
+}
+
+func (s *JobQueue) finish(id string, err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.cancels[id]()
+	delete(s.cancels, id)
+	j, ok := s.jobs[id]
+	if !ok || j.State != JobRunning {
+		s.changed()
+		return
+	}
+	if s.closing && err != nil {
+		j.State = JobQueued
+	} else if err != nil {
+		j.State = JobFailed
+		j.Error = err.Error()
+		j.Finished = time.Now()
+		log.WithError(err).Warnf("job failed id=%v infohash=%v path=%v", id, j.InfoHash, j.Path)
+	} else {
+		j.State = JobCompleted
+		j.Finished = time.Now()
+		log.Infof("job completed id=%v infohash=%v path=%v", id, j.InfoHash, j.Path)
+	}
+	s.changed()
+}
+
+func (s *JobQueue) progress(id string, files int, completedFiles int, bytes int64, completedBytes int64) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return
+	}
+	j.Files = files
+	j.CompletedFiles = completedFiles
+	j.Bytes = bytes
+	j.CompletedBytes = completedBytes
+}
+
+// jobFiles returns torrent files matching job path: the file itself, files
+// of the directory or all files for empty path.
+func jobFiles(t *torrent.Torrent, path string) []*torrent.File {
+	var files []*torrent.File
+	for _, f := range t.Files() {
+		if path == "" || f.Path() == path || strings.HasPrefix(f.Path(), path+"/") {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func (s *JobQueue) run(ctx context.Context, j Job) (err error) {
+	t, release, err := s.tm.Open(ctx, j.InfoHash)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if t == nil {
+		return errors.Wrapf(ErrTorrentNotFound, "infohash=%v", j.InfoHash)
+	}
+	all := jobFiles(t, j.Path)
+	if len(all) == 0 {
+		return errors.Wrapf(ErrFileNotFound, "infohash=%v path=%v", j.InfoHash, j.Path)
+	}
+	dir, err := GetDir(s.dataDir, j.InfoHash)
+	if err != nil {
+		return err
+	}
+	var files []*torrent.File
+	defer func() {
+		if err != nil {
+			for _, f := range files {
+				f.SetPriority(s.tm.FilePriority(j.InfoHash, f.Path()).piecePriority())
+			}
+		}
+	}()
+	log.Infof("job started id=%v infohash=%v path=%v files=%v", j.ID, j.InfoHash, j.Path, len(all))
+	ticker := time.NewTicker(s.poll)
+	defer ticker.Stop()
+	for {
+		s.tm.KeepAlive(j.InfoHash, jobKeepAlive)
+		files = s.download(j.InfoHash, all)
+		paths := make([]string, 0, len(files))
+		var length, completedBytes int64
+		for _, f := range files {
+			paths = append(paths, f.Path())
+			length += f.Length()
+			completedBytes += fileBytesCompleted(f)
+		}
+		completed, err := s.completedFilesCount(j.InfoHash, dir, paths)
+		if err != nil {
+			log.WithError(err).Warnf("failed to check completed files of job id=%v, retrying", j.ID)
+		} else {
+			s.progress(j.ID, len(paths), completed, length, completedBytes)
+			if completed == len(paths) {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// download starts downloading files of torrent h and returns them without
+// skipped ones. File priority chosen by client is kept. It is called on every
+// poll, so priority changes made while job runs are followed.
+func (s *JobQueue) download(h string, all []*torrent.File) []*torrent.File {
+	files := make([]*torrent.File, 0, len(all))
+	for _, f := range all {
+		p := s.tm.FilePriority(h, f.Path())
+		if p == FilePrioritySkip {
+			continue
+		}
+		if p == FilePriorityDefault && f.Priority() == torrent.PiecePriorityNone {
+			f.Download()
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// completedFilesCount returns how many of paths of torrent h are complete.
+// Completion of open torrent is read from its piece completion, torrent db
+// is opened only otherwise.
+func (s *JobQueue) completedFilesCount(h string, dir string, paths []string) (int, error) {
+	if pc := s.tm.tc.pieceCompletion(h); pc != nil {
+		if n, err := pc.completedFilesCount(paths); err == nil {
+			return n, nil
+		}
+	}
+	return completedFilesCount(dir, paths)
+}
+
+// completedFilesCount returns how many of paths are in file_completion table
+// of torrent data dir.
+func completedFilesCount(dir string, paths []string) (int, error) {
+	f := filepath.Join(dir, ".torrent.db")
+	if _, err := os.Stat(f); os.IsNotExist(err) {
+		return 0, nil
+	}
+	db, err := sqlite.OpenConn(f, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer func(db *sqlite.Conn) {
+		_ = db.Close()
+	}(db)
+	want := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		want[p] = true
+	}
+	var count int
+	err = sqlitex.Exec(db, `select "path" from file_completion`,
+		func(stmt *sqlite.Stmt) error {
+			if want[stmt.ColumnText(0)] {
+				count++
+			}
+			return nil
+		})
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *JobQueue) Serve() error {
+	log.Infof("serving JobQueue concurrency=%v", s.concurrency)
+	for {
+		s.schedule()
+		select {
+		case <-s.wakeCh:
+		case <-s.closeCh:
+			return nil
+		}
+	}
+}
+
+// Close stops running jobs, they are resumed on next start.
+func (s *JobQueue) Close() {
+	s.mux.Lock()
+	s.closing = true
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.mux.Unlock()
+	close(s.closeCh)
+	s.wg.Wait()
+}
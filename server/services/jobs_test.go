@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/pkg/errors"
+)
+
+func TestJobQueue_SubmitAndNext(t *testing.T) {
+	q := newJobQueue(t.TempDir(), 1, nil)
+	h := strings.Repeat("a", 40)
+	low, err := q.Submit(h, "low.mkv", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	high, err := q.Submit(strings.ToUpper(h), "/high.mkv", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if high.InfoHash != h || high.Path != "high.mkv" {
+		t.Fatalf("expected normalized job, got %+v", high)
+	}
+	if _, err := q.Submit("nope", "", 0); err == nil {
+		t.Fatal("expected invalid infohash error")
+	}
+
+	// Resubmitting unfinished job raises its priority instead of adding one.
+	again, err := q.Submit(h, "low.mkv", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != low.ID || len(q.List()) != 2 {
+		t.Fatalf("expected job %v reused, got %+v", low.ID, q.List())
+	}
+	if j := q.next(); j.ID != low.ID {
+		t.Fatalf("expected job with highest priority next, got %+v", j)
+	}
+
+	if err := q.Cancel(low.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Cancel(low.ID); !errors.Is(err, ErrJobFinished) {
+		t.Fatalf("expected ErrJobFinished, got %v", err)
+	}
+	if err := q.Cancel("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+	if j := q.next(); j.ID != high.ID {
+		t.Fatalf("expected job %v next, got %+v", high.ID, j)
+	}
+}
+
+func TestJobQueue_Persistence(t *testing.T) {
+	dir := t.TempDir()
+	q := newJobQueue(dir, 1, nil)
+	j, err := q.Submit(strings.Repeat("b", 40), "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.mux.Lock()
+	q.jobs[j.ID].State = JobRunning
+	q.save()
+	q.mux.Unlock()
+
+	r := newJobQueue(dir, 1, nil)
+	if err := r.load(); err != nil {
+		t.Fatal(err)
+	}
+	jobs := r.List()
+	if len(jobs) != 1 || jobs[0].ID != j.ID {
+		t.Fatalf("expected job %v restored, got %+v", j.ID, jobs)
+	}
+	if jobs[0].State != JobQueued {
+		t.Fatalf("expected interrupted job queued again, got %v", jobs[0].State)
+	}
+}
+
+func TestCompletedFilesCount(t *testing.T) {
+	dir := t.TempDir()
+	info := &metainfo.Info{
+		Name:        "t",
+		PieceLength: 16,
+		Pieces:      make([]byte, 20*2),
+		Files: []metainfo.FileInfo{
+			{Path: []string{"a"}, Length: 16},
+			{Path: []string{"b"}, Length: 16},
+		},
+	}
+	n, err := completedFilesCount(dir, []string{"t/a"})
+	if err != nil || n != 0 {
+		t.Fatalf("expected no completed files without db, got %v %v", n, err)
+	}
+	pc, err := NewPieceCompletion(dir, info, metainfo.Hash{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.CompleteFile("t/a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.CompleteFile("other/c"); err != nil {
+		t.Fatal(err)
+	}
+	_ = pc.Close()
+	n, err = completedFilesCount(dir, []string{"t/a", "t/b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 completed file, got %v", n)
+	}
+}
+
+func TestJobQueue_CompletedFilesOfOpenTorrent(t *testing.T) {
+	info := &metainfo.Info{
+		Name:        "t",
+		PieceLength: 16,
+		Pieces:      make([]byte, 20*2),
+		Files: []metainfo.FileInfo{
+			{Path: []string{"a"}, Length: 16},
+			{Path: []string{"b"}, Length: 16},
+		},
+	}
+	h := metainfo.Hash{1}
+	si, err := NewStorage(StorageFile, t.TempDir(), 0, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := newJobQueue(t.TempDir(), 1, &TorrentMap{tc: &TorrentClient{storageImpl: si}})
+	impl, err := si.OpenTorrent(context.Background(), info, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := si.completion(h)
+	if pc == nil {
+		t.Fatal("expected piece completion of open torrent")
+	}
+	if err := pc.CompleteFile("t/a"); err != nil {
+		t.Fatal(err)
+	}
+	// Passed dir has no torrent db, so completion comes from open torrent.
+	n, err := q.completedFilesCount(h.HexString(), t.TempDir(), []string{"t/a", "t/b"})
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 completed file, got %v %v", n, err)
+	}
+	if err := impl.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for si.completion(h) != nil {
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/anacrolix/torrent"
+)
+
+const (
+	jsonContentType = "application/json"
+)
+
+// Listing describes the content of an index page. It is rendered either as
+// bare HTML links or as JSON, depending on what the client asked for.
+type Listing struct {
+	Title    string        `json:"title"`
+	InfoHash string        `json:"info_hash,omitempty"`
+	Name     string        `json:"name,omitempty"`
+	Items    []ListingItem `json:"items"`
+	Total    int           `json:"total,omitempty"`
+	Next     string        `json:"next,omitempty"`
+}
+
+// ListingItem is a single entry of a Listing. File details are present only
+// for entries that point to a file inside a torrent.
+type ListingItem struct {
+	Path     string `json:"path"`
+	InfoHash string `json:"info_hash,omitempty"`
+	*ListingFile
+	*ListingTorrent
+}
+
+// ListingTorrent tells where torrent of an index entry comes from and how
+// much of it is cached.
+type ListingTorrent struct {
+	Sources     []string `json:"sources"`
+	CacheState  string   `json:"cache_state"`
+	CachedBytes int64    `json:"cached_bytes"`
+}
+
+type ListingFile struct {
+	Length     int64   `json:"length"`
+	Offset     int64   `json:"offset"`
+	FirstPiece int     `json:"first_piece"`
+	LastPiece  int     `json:"last_piece"`
+	MimeType   string  `json:"mime_type"`
+	Completion float64 `json:"completion"`
+}
+
+func NewTorrentListing(h string, t *torrent.Torrent) *Listing {
+	l := &Listing{
+		Title:    h,
+		InfoHash: h,
+		Name:     t.Info().Name,
+		Items:    []ListingItem{},
+	}
+	for _, f := range t.Files() {
+		l.Items = append(l.Items, ListingItem{
+			Path:        f.Path(),
+			ListingFile: newListingFile(f),
+		})
+	}
+	return l
+}
+
+func newListingFile(f *torrent.File) *ListingFile {
+	lastPiece := f.EndPieceIndex() - 1
+	if lastPiece < f.BeginPieceIndex() {
+		lastPiece = f.BeginPieceIndex()
+	}
+	var completion float64
+	if f.Length() > 0 {
+		completion = float64(fileBytesCompleted(f)) / float64(f.Length()) * 100
+		completion = math.Round(completion*100) / 100
+	}
+	return &ListingFile{
+		Length:     f.Length(),
+		Offset:     f.Offset(),
+		FirstPiece: f.BeginPieceIndex(),
+		LastPiece:  lastPiece,
+		MimeType:   mimeTypeByPath(f.Path()),
+		Completion: completion,
+	}
+}
+
+func NewIndexListing(items []TorrentIndexItem, total int) *Listing {
+	l := &Listing{
+		Title: "Index",
+		Items: []ListingItem{},
+		Total: total,
+	}
+	for _, i := range items {
+		l.Items = append(l.Items, ListingItem{
+			Path:     i.InfoHash + "/",
+			InfoHash: i.InfoHash,
+			ListingTorrent: &ListingTorrent{
+				Sources:     i.Sources,
+				CacheState:  i.CacheState,
+				CachedBytes: i.CachedBytes,
+			},
+		})
+	}
+	return l
+}
+
+func mimeTypeByPath(p string) string {
+	mt := mime.TypeByExtension(strings.ToLower(filepath.Ext(p)))
+	if mt == "" {
+		return "application/octet-stream"
+	}
+	return mt
+}
+
+// wantsJSON reports whether the client negotiated a JSON response either
+// with ?format=json or with an Accept header.
+func wantsJSON(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "json"
+	}
+	for _, a := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, _, _ := strings.Cut(strings.TrimSpace(a), ";")
+		if mt == jsonContentType {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v any) error {
+	w.Header().Set("Content-Type", jsonContentType)
+	return json.NewEncoder(w).Encode(v)
+}
@@ -38,3 +38,12 @@ func madviseSequential(b []byte) error {
 	}
 	return unix.Madvise(pageAlignedSlice(b), unix.MADV_SEQUENTIAL)
 }
+
+// fadviseEvict advises the kernel that cached pages of the given file range are
+// no longer needed (POSIX_FADV_DONTNEED). Dirty pages are written back first.
+func fadviseEvict(f *os.File, offset, length int64) error {
+	if length <= 0 {
+		return nil
+	}
+	return unix.Fadvise(int(f.Fd()), offset, length, unix.FADV_DONTNEED)
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"github.com/webtor-io/lazymap"
+)
+
+const magnetTorrentSuffix = ".torrent"
+
+// MagnetStoreMap keeps metainfo resolved from magnet links as <hash>.torrent
+// files next to the torrent data directories.
+type MagnetStoreMap struct {
+	lazymap.LazyMap[*metainfo.MetaInfo]
+	p string
+}
+
+func NewMagnetStoreMap(c *cli.Context) *MagnetStoreMap {
+	return &MagnetStoreMap{
+		p: c.String(DataDirFlag),
+		LazyMap: lazymap.New[*metainfo.MetaInfo](&lazymap.Config{
+			Expire:   60 * time.Second,
+			Capacity: 1000,
+		}),
+	}
+}
+
+func (s *MagnetStoreMap) path(h string) (string, error) {
+	dir, err := GetDir(s.p, h)
+	if err != nil {
+		return "", err
+	}
+	return dir + magnetTorrentSuffix, nil
+}
+
+func (s *MagnetStoreMap) get(h string) (*metainfo.MetaInfo, error) {
+	f, err := s.path(h)
+	if err != nil {
+		return nil, err
+	}
+	_, err = os.Stat(f)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	mi, err := metainfo.LoadFromFile(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load cached torrent path=%v", f)
+	}
+	return mi, nil
+}
+
+func (s *MagnetStoreMap) Get(h string) (*metainfo.MetaInfo, error) {
+	return s.LazyMap.Get(h, func() (*metainfo.MetaInfo, error) {
+		return s.get(h)
+	})
+}
+
+// Set writes metainfo to disk. The file is written to a temporary location
+// first so concurrent readers never see a partially written torrent.
+func (s *MagnetStoreMap) Set(h string, mi *metainfo.MetaInfo) error {
+	f, err := s.path(h)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(f), 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f), h+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary torrent file")
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	err = mi.Write(tmp)
+	if err != nil {
+		_ = tmp.Close()
+		return errors.Wrap(err, "failed to write torrent file")
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), f)
+	if err != nil {
+		return errors.Wrap(err, "failed to rename torrent file")
+	}
+	s.LazyMap.Drop(h)
+	return nil
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/webtor-io/lazymap"
+)
+
+const (
+	mediaIndexTimeout = 2 * time.Minute
+	// mediaIndexMaxElements bounds number of top-level boxes/elements visited,
+	// so a broken file never makes us walk through the whole torrent.
+	mediaIndexMaxElements = 64
+	// mediaIndexMaxSeekHead bounds size of MKV SeekHead read into memory.
+	mediaIndexMaxSeekHead = 64 * 1024
+
+	ebmlHeaderID = 0x1A45DFA3
+	mkvSegmentID = 0x18538067
+	mkvSeekHead  = 0x114D9B74
+	mkvSeek      = 0x4DBB
+	mkvSeekID    = 0x53AB
+	mkvSeekPos   = 0x53AC
+	mkvCuesID    = 0x1C53BB6B
+	mkvClusterID = 0x1F43B675
+)
+
+// mediaRange is a byte range [start, end) inside a file.
+type mediaRange struct {
+	start int64
+	end   int64
+}
+
+type mediaIndexParser func(r io.ReadSeeker, size int64) ([]mediaRange, error)
+
+var mediaIndexParsers = map[string]mediaIndexParser{
+	".mp4":  mp4IndexRanges,
+	".m4v":  mp4IndexRanges,
+	".mov":  mp4IndexRanges,
+	".mkv":  mkvIndexRanges,
+	".webm": mkvIndexRanges,
+}
+
+// MediaIndex locates index structures of media containers (MP4 moov, MKV
+// SeekHead and Cues) and raises priority of their pieces, so players that
+// jump to them right after opening the file do not stall.
+type MediaIndex struct {
+	lazymap.LazyMap[bool]
+}
+
+func NewMediaIndex() *MediaIndex {
+	return &MediaIndex{
+		LazyMap: lazymap.New[bool](&lazymap.Config{
+			Expire:   10 * time.Minute,
+			Capacity: 1000,
+		}),
+	}
+}
+
+// Prioritize parses container header of the file in background once per
+// file. It returns immediately. Failed parse is retried on next call, and
+// result is forgotten when torrent is closed, so re-added torrent gets its
+// index pieces prioritized again.
+func (s *MediaIndex) Prioritize(h string, t *torrent.Torrent, f *torrent.File) {
+	parse, ok := mediaIndexParsers[strings.ToLower(filepath.Ext(f.Path()))]
+	if !ok {
+		return
+	}
+	key := h + "/" + f.Path()
+	go func() {
+		_, _ = s.LazyMap.Get(key, func() (bool, error) {
+			err := prioritizeMediaIndex(t, f, parse)
+			if err != nil {
+				log.WithError(err).Warnf("failed to prioritize media index infohash=%v path=%v", h, f.Path())
+				return false, err
+			}
+			go func() {
+				<-t.Closed()
+				s.LazyMap.Drop(key)
+			}()
+			return true, nil
+		})
+	}()
+}
+
+func prioritizeMediaIndex(t *torrent.Torrent, f *torrent.File, parse mediaIndexParser) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mediaIndexTimeout)
+	defer cancel()
+	r := f.NewReader()
+	defer r.Close()
+	r.SetContext(ctx)
+	r.SetReadahead(0)
+	ranges, err := parse(r, f.Length())
+	if err != nil {
+		return err
+	}
+	pieceLength := t.Info().PieceLength
+	for _, mr := range ranges {
+		begin, end := mediaRangePieces(f.Offset(), pieceLength, mr)
+		log.Infof("prioritizing media index path=%v pieces=%v-%v", f.Path(), begin, end-1)
+		for i := begin; i < end; i++ {
+			seederPiecePriorities.Raise(t, i, torrent.PiecePriorityHigh)
+		}
+	}
+	return nil
+}
+
+// mediaRangePieces returns torrent pieces [begin, end) covering file range.
+func mediaRangePieces(fileOffset int64, pieceLength int64, r mediaRange) (int, int) {
+	if r.end <= r.start {
+		return 0, 0
+	}
+	begin := (fileOffset + r.start) / pieceLength
+	end := (fileOffset+r.end-1)/pieceLength + 1
+	return int(begin), int(end)
+}
+
+func readAt(r io.ReadSeeker, off int64, b []byte) error {
+	_, err := r.Seek(off, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	_, err = io.ReadFull(r, b)
+	return err
+}
+
+// mp4IndexRanges walks top-level boxes and returns range of moov box.
+func mp4IndexRanges(r io.ReadSeeker, size int64) ([]mediaRange, error) {
+	var off int64
+	hdr := make([]byte, 16)
+	for i := 0; i < mediaIndexMaxElements && off+8 <= size; i++ {
+		err := readAt(r, off, hdr[:8])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read box header offset=%v", off)
+		}
+		boxSize := int64(binary.BigEndian.Uint32(hdr[:4]))
+		boxType := string(hdr[4:8])
+		headerSize := int64(8)
+		if boxSize == 1 {
+			if off+16 > size {
+				return nil, errors.Errorf("truncated box header offset=%v", off)
+			}
+			err = readAt(r, off+8, hdr[8:16])
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read box size offset=%v", off)
+			}
+			boxSize = int64(binary.BigEndian.Uint64(hdr[8:16]))
+			headerSize = 16
+		} else if boxSize == 0 {
+			boxSize = size - off
+		}
+		if boxSize < headerSize {
+			return nil, errors.Errorf("invalid box size=%v type=%q offset=%v", boxSize, boxType, off)
+		}
+		if boxType == "moov" {
+			return []mediaRange{{off, min(off+boxSize, size)}}, nil
+		}
+		off += boxSize
+	}
+	return nil, nil
+}
+
+// ebmlVint decodes EBML variable length integer. IDs keep their length
+// marker, sizes have it stripped. Unknown size is reported as -1.
+func ebmlVint(b []byte, keepMarker bool) (int64, int, error) {
+	if len(b) == 0 || b[0] == 0 {
+		return 0, 0, errors.New("invalid ebml vint")
+	}
+	l := 1
+	for mask := byte(0x80); b[0]&mask == 0; mask >>= 1 {
+		l++
+	}
+	if len(b) < l {
+		return 0, 0, errors.New("truncated ebml vint")
+	}
+	v := int64(b[0])
+	if !keepMarker {
+		v &= int64(0xFF >> l)
+	}
+	allOnes := v == int64(0xFF>>l)
+	for i := 1; i < l; i++ {
+		v = v<<8 | int64(b[i])
+		allOnes = allOnes && b[i] == 0xFF
+	}
+	if !keepMarker && allOnes {
+		return -1, l, nil
+	}
+	return v, l, nil
+}
+
+// ebmlElement decodes element header, returning id, header length and data size.
+func ebmlElement(b []byte) (int64, int, int64, error) {
+	id, il, err := ebmlVint(b, true)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	size, sl, err := ebmlVint(b[il:], false)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return id, il + sl, size, nil
+}
+
+func readEBMLElement(r io.ReadSeeker, off int64, size int64) (int64, int64, int64, error) {
+	if off >= size {
+		return 0, 0, 0, errors.Errorf("element offset=%v beyond end of file", off)
+	}
+	b := make([]byte, min(12, size-off))
+	err := readAt(r, off, b)
+	if err != nil {
+		return 0, 0, 0, errors.Wrapf(err, "failed to read element header offset=%v", off)
+	}
+	id, hl, ds, err := ebmlElement(b)
+	if err != nil {
+		return 0, 0, 0, errors.Wrapf(err, "failed to parse element header offset=%v", off)
+	}
+	return id, int64(hl), ds, nil
+}
+
+// mkvIndexRanges returns ranges of SeekHead and Cues elements of the first
+// segment. Cues are located through SeekHead or found among top-level
+// elements preceding the first Cluster.
+func mkvIndexRanges(r io.ReadSeeker, size int64) ([]mediaRange, error) {
+	id, hl, ds, err := readEBMLElement(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+	if id != ebmlHeaderID || ds < 0 {
+		return nil, errors.New("not an ebml file")
+	}
+	off := hl + ds
+	id, hl, _, err = readEBMLElement(r, off, size)
+	if err != nil {
+		return nil, err
+	}
+	if id != mkvSegmentID {
+		return nil, errors.Errorf("expected segment got id=%x", id)
+	}
+	segStart := off + hl
+	var ranges []mediaRange
+	cuesPos := int64(-1)
+	pos := segStart
+	for i := 0; i < mediaIndexMaxElements && pos < size; i++ {
+		id, hl, ds, err = readEBMLElement(r, pos, size)
+		if err != nil {
+			return nil, err
+		}
+		if ds < 0 || id == mkvClusterID {
+			break
+		}
+		end := min(pos+hl+ds, size)
+		if id == mkvCuesID {
+			return append(ranges, mediaRange{pos, end}), nil
+		}
+		if id == mkvSeekHead && cuesPos < 0 && ds <= mediaIndexMaxSeekHead {
+			ranges = append(ranges, mediaRange{pos, end})
+			b := make([]byte, end-pos-hl)
+			err = readAt(r, pos+hl, b)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read seek head")
+			}
+			if p, ok := mkvSeekPosition(b, mkvCuesID); ok {
+				cuesPos = segStart + p
+			}
+		}
+		pos = end
+	}
+	if cuesPos < 0 || cuesPos >= size {
+		return ranges, nil
+	}
+	id, hl, ds, err = readEBMLElement(r, cuesPos, size)
+	if err != nil {
+		return nil, err
+	}
+	if id != mkvCuesID || ds < 0 {
+		return ranges, nil
+	}
+	return append(ranges, mediaRange{cuesPos, min(cuesPos+hl+ds, size)}), nil
+}
+
+// mkvSeekPosition searches SeekHead body for position of element with id.
+func mkvSeekPosition(b []byte, target int64) (int64, bool) {
+	for len(b) > 0 {
+		id, hl, ds, err := ebmlElement(b)
+		if err != nil || ds < 0 || int64(hl)+ds > int64(len(b)) {
+			return 0, false
+		}
+		body := b[hl : int64(hl)+ds]
+		b = b[int64(hl)+ds:]
+		if id != mkvSeek {
+			continue
+		}
+		var seekID, seekPos int64 = 0, -1
+		for len(body) > 0 {
+			cid, chl, cds, err := ebmlElement(body)
+			if err != nil || cds < 0 || int64(chl)+cds > int64(len(body)) {
+				return 0, false
+			}
+			v := body[chl : int64(chl)+cds]
+			body = body[int64(chl)+cds:]
+			var n int64
+			for _, c := range v {
+				n = n<<8 | int64(c)
+			}
+			if cid == mkvSeekID {
+				seekID = n
+			} else if cid == mkvSeekPos {
+				seekPos = n
+			}
+		}
+		if seekID == target && seekPos >= 0 {
+			return seekPos, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func mp4Box(typ string, body []byte) []byte {
+	b := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(b, uint32(8+len(body)))
+	copy(b[4:], typ)
+	return append(b, body...)
+}
+
+func mp4LargeBox(typ string, body []byte) []byte {
+	b := make([]byte, 16, 16+len(body))
+	binary.BigEndian.PutUint32(b, 1)
+	copy(b[4:], typ)
+	binary.BigEndian.PutUint64(b[8:], uint64(16+len(body)))
+	return append(b, body...)
+}
+
+func TestMP4IndexRanges_MoovAtTail(t *testing.T) {
+	ftyp := mp4Box("ftyp", []byte("isom0000"))
+	mdat := mp4LargeBox("mdat", bytes.Repeat([]byte{0xAA}, 100000))
+	moov := mp4Box("moov", mp4Box("mvhd", make([]byte, 100)))
+	data := bytes.Join([][]byte{ftyp, mdat, moov}, nil)
+	ranges, err := mp4IndexRanges(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := int64(len(ftyp) + len(mdat))
+	if len(ranges) != 1 || ranges[0] != (mediaRange{start, int64(len(data))}) {
+		t.Fatalf("unexpected ranges %v", ranges)
+	}
+}
+
+func TestMP4IndexRanges_InvalidBox(t *testing.T) {
+	data := []byte{0, 0, 0, 4, 'f', 't', 'y', 'p'}
+	_, err := mp4IndexRanges(bytes.NewReader(data), int64(len(data)))
+	if err == nil {
+		t.Fatal("expected error for invalid box size")
+	}
+}
+
+func ebmlID(id uint32) []byte {
+	b := binary.BigEndian.AppendUint32(nil, id)
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	return b
+}
+
+func ebml(id uint32, body []byte) []byte {
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(body)))
+	size[0] = 0x01
+	b := append(ebmlID(id), size...)
+	return append(b, body...)
+}
+
+func ebmlUint(id uint32, v uint64) []byte {
+	return ebml(id, binary.BigEndian.AppendUint64(nil, v))
+}
+
+func mkvSeekEntry(id uint32, pos uint64) []byte {
+	return ebml(mkvSeek, append(ebml(mkvSeekID, ebmlID(id)), ebmlUint(mkvSeekPos, pos)...))
+}
+
+func TestMKVIndexRanges_CuesFromSeekHead(t *testing.T) {
+	header := ebml(ebmlHeaderID, []byte("webm"))
+	info := ebml(0x1549A966, make([]byte, 20))
+	cluster := ebml(mkvClusterID, bytes.Repeat([]byte{0xBB}, 50000))
+	cues := ebml(mkvCuesID, make([]byte, 300))
+	// SeekHead size does not depend on positions, so compute it with a placeholder first.
+	seekHeadLen := len(ebml(mkvSeekHead, mkvSeekEntry(mkvCuesID, 0)))
+	cuesPos := seekHeadLen + len(info) + len(cluster)
+	seekHead := ebml(mkvSeekHead, mkvSeekEntry(mkvCuesID, uint64(cuesPos)))
+	body := bytes.Join([][]byte{seekHead, info, cluster, cues}, nil)
+	// Segment with unknown size, as written by live muxers.
+	segment := append(append(ebmlID(mkvSegmentID), 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF), body...)
+	data := append(header, segment...)
+
+	ranges, err := mkvIndexRanges(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	segStart := int64(len(header) + 4 + 8)
+	expected := []mediaRange{
+		{segStart, segStart + int64(len(seekHead))},
+		{segStart + int64(cuesPos), int64(len(data))},
+	}
+	if len(ranges) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, ranges)
+	}
+	for i := range expected {
+		if ranges[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, ranges)
+		}
+	}
+}
+
+func TestMKVIndexRanges_CuesBeforeClusters(t *testing.T) {
+	header := ebml(ebmlHeaderID, []byte("matroska"))
+	cues := ebml(mkvCuesID, make([]byte, 10))
+	cluster := ebml(mkvClusterID, make([]byte, 1000))
+	data := append(header, ebml(mkvSegmentID, append(cues, cluster...))...)
+	ranges, err := mkvIndexRanges(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := int64(len(header) + 4 + 8)
+	if len(ranges) != 1 || ranges[0] != (mediaRange{start, start + int64(len(cues))}) {
+		t.Fatalf("unexpected ranges %v", ranges)
+	}
+}
+
+func TestMKVIndexRanges_NotEBML(t *testing.T) {
+	data := mp4Box("ftyp", []byte("isom"))
+	if _, err := mkvIndexRanges(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Fatal("expected error for non ebml data")
+	}
+}
+
+func TestMediaRangePieces_Sintel(t *testing.T) {
+	mi, err := metainfo.LoadFromFile("../../torrents/Sintel.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offset, length int64 = -1, 0
+	var o int64
+	for _, f := range info.UpvertedFiles() {
+		if strings.HasSuffix(f.DisplayPath(&info), ".mp4") {
+			offset, length = o, f.Length
+		}
+		o += f.Length
+	}
+	if offset < 0 {
+		t.Fatal("no mp4 file in fixture")
+	}
+	// moov at the tail of the file must map to pieces ending at the piece holding file's last byte.
+	begin, end := mediaRangePieces(offset, info.PieceLength, mediaRange{length - 200000, length})
+	lastPiece := int((offset + length - 1) / info.PieceLength)
+	if end != lastPiece+1 {
+		t.Fatalf("expected end piece %d, got %d", lastPiece+1, end)
+	}
+	if begin > end-2 || begin < 0 || end > info.NumPieces() {
+		t.Fatalf("unexpected piece range %d-%d of %d", begin, end, info.NumPieces())
+	}
+	if b, e := mediaRangePieces(offset, info.PieceLength, mediaRange{0, 0}); b != e {
+		t.Fatalf("expected empty piece range for empty media range, got %d-%d", b, e)
+	}
+}
@@ -24,7 +24,7 @@ func TestPieceFileRegions_SingleFile(t *testing.T) {
 		Name:        "test",
 		Pieces:      makeDummyPieces(5),
 	}
-	ts := &mmapTorrentStorage{
+	ts := &torrentStorage{
 		info:     info,
 		fileLens: []int64{500},
 	}
@@ -63,7 +63,7 @@ func TestPieceFileRegions_MultiFile(t *testing.T) {
 			{Length: 150, Path: []string{"b.txt"}},
 		},
 	}
-	ts := &mmapTorrentStorage{
+	ts := &torrentStorage{
 		info:     info,
 		fileLens: []int64{150, 150},
 	}
@@ -108,7 +108,7 @@ func TestPieceFileRegions_LastPieceShorter(t *testing.T) {
 		Name:        "test",
 		Pieces:      makeDummyPieces(3),
 	}
-	ts := &mmapTorrentStorage{
+	ts := &torrentStorage{
 		info:     info,
 		fileLens: []int64{250},
 	}
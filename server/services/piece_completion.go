@@ -94,6 +94,12 @@ func (s *completions) IsPieceInCompletedFile(index int) bool {
 	return false
 }
 
+func (s *completions) isCompleted() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.completed
+}
+
 func (s *completions) GetCompletedFiles() []string {
 	s.mux.Lock()
 	defer s.mux.Unlock()
@@ -146,11 +152,12 @@ type pieceCompletion struct {
 	info        *metainfo.Info
 	hash        metainfo.Hash
 	completions *completions
+	recorded    map[string]bool // paths in file_completion table
 }
 
 var _ storage.PieceCompletion = (*pieceCompletion)(nil)
 
-func NewPieceCompletion(dir string, info *metainfo.Info, hash metainfo.Hash) (ret *pieceCompletion, err error) {
+func NewPieceCompletion(dir string, info *metainfo.Info, hash metainfo.Hash, wh *Webhooks) (ret *pieceCompletion, err error) {
 	p := filepath.Join(dir, ".torrent.db")
 	db, err := sqlite.OpenConn(p, 0)
 	if err != nil {
@@ -166,6 +173,11 @@ func NewPieceCompletion(dir string, info *metainfo.Info, hash metainfo.Hash) (re
 		_ = db.Close()
 		return
 	}
+	err = sqlitex.ExecScript(db, `create table if not exists file_crc("path", crc, unique("path"))`)
+	if err != nil {
+		_ = db.Close()
+		return
+	}
 	pieces := make([]bool, info.NumPieces())
 	for i := 0; i < info.NumPieces(); i++ {
 		pieces[i] = false
@@ -185,6 +197,18 @@ func NewPieceCompletion(dir string, info *metainfo.Info, hash metainfo.Hash) (re
 		_ = db.Close()
 		return
 	}
+	recorded := map[string]bool{}
+	err = sqlitex.Exec(db, `select "path" from file_completion`,
+		func(stmt *sqlite.Stmt) error {
+			recorded[stmt.ColumnText(0)] = true
+			return nil
+		},
+	)
+	if err != nil {
+		_ = db.Close()
+		return
+	}
+	wasCompleted := completedCount == len(pieces)
 	completions := &completions{
 		pieces:         pieces,
 		completedCount: completedCount,
@@ -197,19 +221,16 @@ func NewPieceCompletion(dir string, info *metainfo.Info, hash metainfo.Hash) (re
 		info:        info,
 		hash:        hash,
 		completions: completions,
+		recorded:    recorded,
 	}
 	go func() {
-		// No local dedup map — always call CompleteFile() so that after
-		// eviction + re-download the file_completion entry is re-added.
-		// INSERT OR REPLACE is idempotent, so repeated calls are safe.
-		for {
-			for _, f := range completions.GetCompletedFiles() {
-				err = ret.CompleteFile(f)
-				if err != nil {
-					return
-				}
-			}
-			if completions.completed || ret.closed {
+		// Polling continues until completion is closed, so events fire again
+		// once evicted pieces are downloaded again.
+		completed := wasCompleted
+		for !ret.isClosed() {
+			var err error
+			completed, err = ret.notify(wh, completed)
+			if err != nil {
 				return
 			}
 			<-time.After(5 * time.Second)
@@ -218,6 +239,29 @@ func NewPieceCompletion(dir string, info *metainfo.Info, hash metainfo.Hash) (re
 	return
 }
 
+// notify records completed files and emits events of files completed since
+// previous call. It emits torrent.completed if torrent got complete after
+// wasCompleted state and returns current state.
+func (s *pieceCompletion) notify(wh *Webhooks, wasCompleted bool) (bool, error) {
+	// No local dedup map — always call completeFile() so that after
+	// eviction + re-download the file_completion entry is re-added.
+	// INSERT OR REPLACE is idempotent, so repeated calls are safe.
+	for _, f := range s.completions.GetCompletedFiles() {
+		added, err := s.completeFile(f)
+		if err != nil {
+			return wasCompleted, err
+		}
+		if added {
+			wh.Emit(WebhookEvent{Type: EventFileCompleted, InfoHash: s.hash.HexString(), Path: f})
+		}
+	}
+	completed := s.completions.isCompleted()
+	if completed && !wasCompleted {
+		wh.Emit(WebhookEvent{Type: EventTorrentCompleted, InfoHash: s.hash.HexString(), Path: s.info.Name})
+	}
+	return completed, nil
+}
+
 func (s *pieceCompletion) Get(pk metainfo.PieceKey) (c storage.Completion, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -265,22 +309,58 @@ func (s *pieceCompletion) UncompleteFiles(paths []string) error {
 		if err != nil {
 			return err
 		}
+		delete(s.recorded, p)
 	}
 	return nil
 }
 
 func (s *pieceCompletion) CompleteFile(path string) error {
+	_, err := s.completeFile(path)
+	return err
+}
+
+// completeFile records file in file_completion table and reports whether it
+// was not recorded before.
+func (s *pieceCompletion) completeFile(path string) (bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.closed {
-		return errors.New("closed")
+		return false, errors.New("closed")
 	}
-	return sqlitex.Exec(
+	err := sqlitex.Exec(
 		s.db,
 		`insert or replace into file_completion("path") values(?)`,
 		nil,
 		path,
 	)
+	if err != nil {
+		return false, err
+	}
+	added := !s.recorded[path]
+	s.recorded[path] = true
+	return added, nil
+}
+
+// completedFilesCount returns how many of paths are in file_completion table.
+func (s *pieceCompletion) completedFilesCount(paths []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, errors.New("closed")
+	}
+	var count int
+	for _, p := range paths {
+		if s.recorded[p] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *pieceCompletion) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
 }
 
 func (s *pieceCompletion) Close() (err error) {
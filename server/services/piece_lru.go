@@ -146,6 +146,25 @@ func (l *PieceLRU) Recover(completePieces map[int]int64) {
 	}
 }
 
+// evictionCandidates returns all tracked pieces from LRU end, used by
+// CacheBudget to pick globally least recently used pieces.
+func (l *PieceLRU) evictionCandidates() []evictionCandidate {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	res := make([]evictionCandidate, 0, len(l.entries))
+	for el := l.lruList.Back(); el != nil; el = el.Prev() {
+		e := el.Value.(*pieceEntry)
+		res = append(res, evictionCandidate{
+			lru:        l,
+			index:      e.index,
+			size:       e.size,
+			lastAccess: e.lastAccess,
+			protected:  l.isProtected != nil && l.isProtected(e.index),
+		})
+	}
+	return res
+}
+
 // computeEvictions returns piece indices to evict (from LRU end) to bring used <= budget.
 // Two-pass strategy:
 //   - Pass 1: evict pieces NOT belonging to completed files (safe, no race with file cache).
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/anacrolix/torrent"
+)
+
+// piecePriorities keeps priorities that seeder itself sets on pieces. Media
+// index and prefetch raise priority permanently, while requests waiting for a
+// piece hold it at PiecePriorityNow only while they wait. Holds are reference
+// counted, so the last waiter restores the highest permanent priority instead
+// of resetting the piece to none under other waiters.
+type piecePriorities struct {
+	mux      sync.Mutex
+	torrents map[*torrent.Torrent]map[int]*piecePriorityState
+	set      func(t *torrent.Torrent, i int, p torrent.PiecePriority)
+	closed   func(t *torrent.Torrent) <-chan struct{}
+}
+
+type piecePriorityState struct {
+	base  torrent.PiecePriority
+	holds int
+}
+
+func (s *piecePriorityState) priority() torrent.PiecePriority {
+	if s.holds > 0 {
+		return torrent.PiecePriorityNow
+	}
+	return s.base
+}
+
+var seederPiecePriorities = newPiecePriorities(
+	func(t *torrent.Torrent, i int, p torrent.PiecePriority) {
+		t.Piece(i).SetPriority(p)
+	},
+	func(t *torrent.Torrent) <-chan struct{} {
+		return t.Closed()
+	},
+)
+
+func newPiecePriorities(set func(t *torrent.Torrent, i int, p torrent.PiecePriority), closed func(t *torrent.Torrent) <-chan struct{}) *piecePriorities {
+	return &piecePriorities{
+		torrents: map[*torrent.Torrent]map[int]*piecePriorityState{},
+		set:      set,
+		closed:   closed,
+	}
+}
+
+// state returns state of piece i, s.mux must be held. State of torrent is
+// forgotten once torrent is closed.
+func (s *piecePriorities) state(t *torrent.Torrent, i int) *piecePriorityState {
+	pieces, ok := s.torrents[t]
+	if !ok {
+		pieces = map[int]*piecePriorityState{}
+		s.torrents[t] = pieces
+		go func() {
+			<-s.closed(t)
+			s.mux.Lock()
+			delete(s.torrents, t)
+			s.mux.Unlock()
+		}()
+	}
+	st, ok := pieces[i]
+	if !ok {
+		st = &piecePriorityState{}
+		pieces[i] = st
+	}
+	return st
+}
+
+// Raise raises priority of piece i permanently. It never lowers priority.
+func (s *piecePriorities) Raise(t *torrent.Torrent, i int, p torrent.PiecePriority) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	st := s.state(t, i)
+	if p <= st.base {
+		return
+	}
+	prev := st.priority()
+	st.base = p
+	if st.priority() != prev {
+		s.set(t, i, st.priority())
+	}
+}
+
+// Hold raises priority of piece i to PiecePriorityNow until returned release
+// function is called.
+func (s *piecePriorities) Hold(t *torrent.Torrent, i int) (release func()) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	st := s.state(t, i)
+	st.holds++
+	if st.holds == 1 && st.base < torrent.PiecePriorityNow {
+		s.set(t, i, torrent.PiecePriorityNow)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mux.Lock()
+			defer s.mux.Unlock()
+			st.holds--
+			if st.holds > 0 {
+				return
+			}
+			pieces, ok := s.torrents[t]
+			if !ok || pieces[i] != st {
+				// Torrent is closed already.
+				return
+			}
+			if st.base < torrent.PiecePriorityNow {
+				s.set(t, i, st.base)
+			}
+			if st.base == torrent.PiecePriorityNone {
+				delete(pieces, i)
+			}
+		})
+	}
+}
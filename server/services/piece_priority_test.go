@@ -0,0 +1,82 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+func newTestPiecePriorities() (*piecePriorities, map[int]torrent.PiecePriority, chan struct{}) {
+	set := map[int]torrent.PiecePriority{}
+	closed := make(chan struct{})
+	s := newPiecePriorities(
+		func(_ *torrent.Torrent, i int, p torrent.PiecePriority) {
+			set[i] = p
+		},
+		func(_ *torrent.Torrent) <-chan struct{} {
+			return closed
+		},
+	)
+	return s, set, closed
+}
+
+func TestPiecePriorities_ConcurrentHolds(t *testing.T) {
+	s, set, _ := newTestPiecePriorities()
+	tr := &torrent.Torrent{}
+
+	first := s.Hold(tr, 1)
+	second := s.Hold(tr, 1)
+	if set[1] != torrent.PiecePriorityNow {
+		t.Fatalf("expected now priority, got %v", set[1])
+	}
+	first()
+	first()
+	if set[1] != torrent.PiecePriorityNow {
+		t.Fatalf("expected now priority while second waiter holds piece, got %v", set[1])
+	}
+	second()
+	if set[1] != torrent.PiecePriorityNone {
+		t.Fatalf("expected none priority after last waiter, got %v", set[1])
+	}
+}
+
+func TestPiecePriorities_RaiseWhileHeld(t *testing.T) {
+	s, set, _ := newTestPiecePriorities()
+	tr := &torrent.Torrent{}
+
+	release := s.Hold(tr, 2)
+	s.Raise(tr, 2, torrent.PiecePriorityHigh)
+	if set[2] != torrent.PiecePriorityNow {
+		t.Fatalf("expected now priority while held, got %v", set[2])
+	}
+	release()
+	if set[2] != torrent.PiecePriorityHigh {
+		t.Fatalf("expected raised priority restored, got %v", set[2])
+	}
+	s.Raise(tr, 2, torrent.PiecePriorityNormal)
+	if set[2] != torrent.PiecePriorityHigh {
+		t.Fatalf("expected raise never lowers priority, got %v", set[2])
+	}
+}
+
+func TestPiecePriorities_ReleaseAfterClose(t *testing.T) {
+	s, set, closed := newTestPiecePriorities()
+	tr := &torrent.Torrent{}
+
+	release := s.Hold(tr, 3)
+	close(closed)
+	for {
+		s.mux.Lock()
+		_, ok := s.torrents[tr]
+		s.mux.Unlock()
+		if !ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	release()
+	if set[3] != torrent.PiecePriorityNow {
+		t.Fatalf("expected closed torrent left untouched, got %v", set[3])
+	}
+}
@@ -4,19 +4,32 @@ import (
 	"io"
 
 	"github.com/anacrolix/torrent"
+	"github.com/pkg/errors"
 )
 
+// PieceReader constrains torrent.Reader to the bytes of a single piece.
 type PieceReader struct {
-	p *torrent.Piece
-	r torrent.Reader
+	p   *torrent.Piece
+	r   torrent.Reader
+	pos int64
 }
 
 func NewPieceReader(r torrent.Reader, p *torrent.Piece) *PieceReader {
+	_, _ = r.Seek(p.Info().Offset(), io.SeekStart)
 	return &PieceReader{p: p, r: r}
 }
 
 func (s *PieceReader) Read(p []byte) (n int, err error) {
-	return s.r.Read(p)
+	left := s.p.Info().Length() - s.pos
+	if left <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err = s.r.Read(p)
+	s.pos += int64(n)
+	return
 }
 
 func (s *PieceReader) Close() error {
@@ -24,28 +37,27 @@ func (s *PieceReader) Close() error {
 }
 
 func (s *PieceReader) Seek(offset int64, whence int) (int64, error) {
-	pieceOffset := int64(s.p.Info().Offset())
-	pieceLength := s.p.Info().Length() + offset
+	pieceOffset := s.p.Info().Offset()
+	pieceLength := s.p.Info().Length()
 
+	var pos int64
 	switch whence {
 	case io.SeekStart:
-		_, err := s.r.Seek(pieceOffset+offset, io.SeekStart)
-		if err != nil {
-			return 0, err
-		}
-		return offset, nil
+		pos = offset
 	case io.SeekCurrent:
-		n, err := s.r.Seek(offset, io.SeekCurrent)
-		if err != nil {
-			return 0, err
-		}
-		return n - pieceOffset, nil
+		pos = s.pos + offset
 	case io.SeekEnd:
-		n, err := s.r.Seek(pieceOffset+pieceLength+offset, io.SeekStart)
-		if err != nil {
-			return 0, err
-		}
-		return n - pieceOffset, nil
+		pos = pieceLength + offset
+	default:
+		return 0, errors.Errorf("invalid whence %v", whence)
+	}
+	if pos < 0 {
+		return 0, errors.New("negative position")
+	}
+	_, err := s.r.Seek(pieceOffset+pos, io.SeekStart)
+	if err != nil {
+		return 0, err
 	}
-	return 0, nil
+	s.pos = pos
+	return pos, nil
 }
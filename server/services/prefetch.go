@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/bytefmt"
+	"github.com/anacrolix/torrent"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	PrefetchSizeFlag   = "prefetch-size"
+	PrefetchWindowFlag = "prefetch-window"
+)
+
+var (
+	ErrTorrentNotFound = errors.New("torrent not found")
+	ErrFileNotFound    = errors.New("file not found")
+)
+
+func RegisterPrefetchFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.StringFlag{
+			Name:   PrefetchSizeFlag,
+			Usage:  "default size of file head and tail to prefetch",
+			Value:  "16MB",
+			EnvVar: "PREFETCH_SIZE",
+		},
+		cli.DurationFlag{
+			Name:   PrefetchWindowFlag,
+			Usage:  "time prefetched torrent is kept active",
+			Value:  10 * time.Minute,
+			EnvVar: "PREFETCH_WINDOW",
+		},
+	)
+}
+
+// PrefetchResult describes pieces queued by prefetch.
+type PrefetchResult struct {
+	InfoHash   string  `json:"info_hash"`
+	Path       string  `json:"path"`
+	Pieces     int     `json:"pieces"`
+	Complete   int     `json:"complete"`
+	KeepAlive  float64 `json:"keep_alive_seconds"`
+	FirstPiece int     `json:"first_piece"`
+	LastPiece  int     `json:"last_piece"`
+}
+
+// Prefetcher starts downloading parts of a file before a player opens it:
+// head and tail of the file, where containers keep their headers and indexes,
+// or an explicit byte range.
+type Prefetcher struct {
+	tm     *TorrentMap
+	size   int64
+	window time.Duration
+}
+
+func NewPrefetcher(c *cli.Context, tm *TorrentMap) (*Prefetcher, error) {
+	size, err := bytefmt.ToBytes(c.String(PrefetchSizeFlag))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse prefetch size flag")
+	}
+	return &Prefetcher{
+		tm:     tm,
+		size:   int64(size),
+		window: c.Duration(PrefetchWindowFlag),
+	}, nil
+}
+
+// prefetchRanges returns head and tail ranges of size bytes of a file with
+// given length, or explicit range clipped to the file.
+func prefetchRanges(length int64, size int64, explicit *mediaRange) []mediaRange {
+	if explicit != nil {
+		r := mediaRange{max(explicit.start, 0), min(explicit.end, length)}
+		if r.end <= r.start {
+			return nil
+		}
+		return []mediaRange{r}
+	}
+	if size <= 0 || length <= 0 {
+		return nil
+	}
+	if 2*size >= length {
+		return []mediaRange{{0, length}}
+	}
+	return []mediaRange{{0, size}, {length - size, length}}
+}
+
+// Prefetch raises priority of pieces covering file head and tail, or
+// explicit range if set, and keeps torrent active for prefetch window. size
+// overrides default head and tail size when positive. It returns immediately.
+func (s *Prefetcher) Prefetch(ctx context.Context, h string, path string, size int64, explicit *mediaRange) (*PrefetchResult, error) {
+	t, err := s.tm.Get(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, errors.Wrapf(ErrTorrentNotFound, "infohash=%v", h)
+	}
+	f := findFile(t, path)
+	if f == nil {
+		return nil, errors.Wrapf(ErrFileNotFound, "infohash=%v path=%v", h, path)
+	}
+	if size <= 0 {
+		size = s.size
+	}
+	res := &PrefetchResult{
+		InfoHash:   h,
+		Path:       path,
+		KeepAlive:  s.window.Seconds(),
+		FirstPiece: -1,
+		LastPiece:  -1,
+	}
+	pieceLength := t.Info().PieceLength
+	for _, r := range prefetchRanges(f.Length(), size, explicit) {
+		begin, end := mediaRangePieces(f.Offset(), pieceLength, r)
+		if res.FirstPiece < 0 || begin < res.FirstPiece {
+			res.FirstPiece = begin
+		}
+		res.LastPiece = max(res.LastPiece, end-1)
+		for i := begin; i < end; i++ {
+			ps := t.Piece(i).State()
+			res.Pieces++
+			if ps.Complete {
+				res.Complete++
+				continue
+			}
+			seederPiecePriorities.Raise(t, i, torrent.PiecePriorityHigh)
+		}
+	}
+	s.tm.KeepAlive(h, s.window)
+	log.Infof("prefetching infohash=%v path=%v pieces=%v complete=%v", h, path, res.Pieces, res.Complete)
+	return res, nil
+}
+
+// parsePrefetchRange parses single range of Range header ("bytes=0-1023" or
+// "bytes=1024-"). Empty header means no explicit range.
+func parsePrefetchRange(v string) (*mediaRange, error) {
+	if v == "" {
+		return nil, nil
+	}
+	spec, ok := strings.CutPrefix(v, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return nil, errors.Errorf("invalid prefetch range %q", v)
+	}
+	start, end, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok || start == "" {
+		return nil, errors.Errorf("invalid prefetch range %q", v)
+	}
+	s, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || s < 0 {
+		return nil, errors.Errorf("invalid prefetch range %q", v)
+	}
+	r := &mediaRange{start: s, end: math.MaxInt64}
+	if end != "" {
+		e, err := strconv.ParseInt(end, 10, 64)
+		if err != nil || e < s {
+			return nil, errors.Errorf("invalid prefetch range %q", v)
+		}
+		r.end = e + 1
+	}
+	return r, nil
+}
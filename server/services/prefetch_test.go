@@ -0,0 +1,67 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPrefetchRanges(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		length   int64
+		size     int64
+		explicit *mediaRange
+		expected []mediaRange
+	}{
+		{"head and tail", 1000, 100, nil, []mediaRange{{0, 100}, {900, 1000}}},
+		{"overlapping head and tail", 150, 100, nil, []mediaRange{{0, 150}}},
+		{"explicit", 1000, 100, &mediaRange{200, 300}, []mediaRange{{200, 300}}},
+		{"explicit open ended", 1000, 100, &mediaRange{200, math.MaxInt64}, []mediaRange{{200, 1000}}},
+		{"explicit beyond file", 1000, 100, &mediaRange{2000, 3000}, nil},
+		{"empty file", 0, 100, nil, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rs := prefetchRanges(c.length, c.size, c.explicit)
+			if len(rs) != len(c.expected) {
+				t.Fatalf("expected %v, got %v", c.expected, rs)
+			}
+			for i := range rs {
+				if rs[i] != c.expected[i] {
+					t.Fatalf("expected %v, got %v", c.expected, rs)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePrefetchRange(t *testing.T) {
+	r, err := parsePrefetchRange("bytes=10-19")
+	if err != nil || *r != (mediaRange{10, 20}) {
+		t.Fatalf("unexpected range %v %v", r, err)
+	}
+	r, err = parsePrefetchRange("bytes=10-")
+	if err != nil || *r != (mediaRange{10, math.MaxInt64}) {
+		t.Fatalf("unexpected range %v %v", r, err)
+	}
+	r, err = parsePrefetchRange("")
+	if err != nil || r != nil {
+		t.Fatalf("expected no range, got %v %v", r, err)
+	}
+	for _, v := range []string{"bytes=-10", "bytes=20-10", "bytes=0-1,5-6", "0-10"} {
+		if _, err := parsePrefetchRange(v); err == nil {
+			t.Fatalf("expected error for %q", v)
+		}
+	}
+}
+
+func TestTorrentEntry_IdleTimeout(t *testing.T) {
+	e := &torrentEntry{ttl: time.Minute}
+	if d := e.idleTimeout(); d != time.Minute {
+		t.Fatalf("expected ttl without keep alive, got %v", d)
+	}
+	e.keepUntil = time.Now().Add(time.Hour)
+	if d := e.idleTimeout(); d <= 59*time.Minute {
+		t.Fatalf("expected keep alive window, got %v", d)
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// readaheadSampleInterval is how often consumer and swarm rates are sampled.
+	readaheadSampleInterval = time.Second
+	// readaheadWarmup is how long reader is served with max readahead before
+	// enough samples are collected to estimate its bitrate.
+	readaheadWarmup = 3 * time.Second
+	// readaheadIdleTimeout marks reader idle if nothing was written to client.
+	readaheadIdleTimeout = 10 * time.Second
+	// readaheadBuffer is amount of playback readahead should cover when swarm
+	// keeps up with consumer.
+	readaheadBuffer = 30 * time.Second
+	// readaheadEWMAAlpha is smoothing factor of rate estimates.
+	readaheadEWMAAlpha = 0.3
+	readaheadMinFactor = 0.5
+	readaheadMaxFactor = 4.0
+)
+
+var (
+	promReaderReadaheadBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "torrent_web_seeder_reader_readahead_bytes",
+		Help:    "Readahead chosen for torrent readers",
+		Buckets: prometheus.ExponentialBuckets(256*1024, 2, 12),
+	})
+	promReaderBitrate = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "torrent_web_seeder_reader_bitrate_bytes_per_second",
+		Help:    "Estimated consumption rate of torrent readers",
+		Buckets: prometheus.ExponentialBuckets(16*1024, 2, 14),
+	})
+)
+
+func init() {
+	prometheus.MustRegister(promReaderReadaheadBytes)
+	prometheus.MustRegister(promReaderBitrate)
+}
+
+// AdaptiveReadahead estimates how fast a client consumes data of a single
+// reader and how fast the swarm delivers it, and sizes readahead accordingly.
+// Rates are sampled from the HTTP write path, because readahead func is called
+// with torrent client lock held and must not query torrent stats itself.
+type AdaptiveReadahead struct {
+	t          *torrent.Torrent
+	min        int64
+	max        int64
+	mux        sync.Mutex
+	start      time.Time
+	lastSample time.Time
+	lastWrite  time.Time
+	written    int64
+	lastSwarm  int64
+	bitrate    float64
+	swarmRate  float64
+	sampled    bool
+}
+
+func NewAdaptiveReadahead(t *torrent.Torrent, min int64, max int64) *AdaptiveReadahead {
+	if min > max {
+		min = max
+	}
+	now := time.Now()
+	return &AdaptiveReadahead{
+		t:          t,
+		min:        min,
+		max:        max,
+		start:      now,
+		lastSample: now,
+		lastWrite:  now,
+		lastSwarm:  swarmBytesRead(t),
+	}
+}
+
+func swarmBytesRead(t *torrent.Torrent) int64 {
+	stats := t.Stats()
+	return stats.BytesReadUsefulData.Int64()
+}
+
+// Observe records n bytes written to the client.
+func (s *AdaptiveReadahead) Observe(n int) {
+	now := time.Now()
+	s.mux.Lock()
+	s.written += int64(n)
+	s.lastWrite = now
+	elapsed := now.Sub(s.lastSample)
+	if elapsed < readaheadSampleInterval {
+		s.mux.Unlock()
+		return
+	}
+	written := s.written
+	s.written = 0
+	s.lastSample = now
+	s.mux.Unlock()
+
+	swarm := swarmBytesRead(s.t)
+
+	s.mux.Lock()
+	s.bitrate = ewma(s.bitrate, float64(written)/elapsed.Seconds(), s.sampled)
+	s.swarmRate = ewma(s.swarmRate, float64(swarm-s.lastSwarm)/elapsed.Seconds(), s.sampled)
+	s.lastSwarm = swarm
+	s.sampled = true
+	bitrate := s.bitrate
+	s.mux.Unlock()
+
+	promReaderBitrate.Observe(bitrate)
+	promReaderReadaheadBytes.Observe(float64(s.Readahead()))
+}
+
+func ewma(prev float64, cur float64, warm bool) float64 {
+	if !warm {
+		return cur
+	}
+	return readaheadEWMAAlpha*cur + (1-readaheadEWMAAlpha)*prev
+}
+
+// Readahead returns readahead for the current consumer and swarm rates.
+func (s *AdaptiveReadahead) Readahead() int64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastWrite) > readaheadIdleTimeout {
+		return s.min
+	}
+	if !s.sampled || now.Sub(s.start) < readaheadWarmup {
+		return s.max
+	}
+	return adaptiveReadahead(s.bitrate, s.swarmRate, s.min, s.max)
+}
+
+// Func returns readahead func to be used with torrent.Reader.
+func (s *AdaptiveReadahead) Func() torrent.ReadaheadFunc {
+	return func(_ torrent.ReadaheadContext) int64 {
+		return s.Readahead()
+	}
+}
+
+// adaptiveReadahead covers readaheadBuffer of playback at consumer bitrate,
+// scaled up when swarm is slower than consumer and down when it is faster.
+func adaptiveReadahead(bitrate float64, swarmRate float64, min int64, max int64) int64 {
+	if bitrate <= 0 {
+		return min
+	}
+	factor := readaheadMaxFactor
+	if swarmRate > 0 {
+		factor = bitrate / swarmRate
+	}
+	if factor < readaheadMinFactor {
+		factor = readaheadMinFactor
+	} else if factor > readaheadMaxFactor {
+		factor = readaheadMaxFactor
+	}
+	ra := int64(bitrate * readaheadBuffer.Seconds() * factor)
+	if ra < min {
+		return min
+	}
+	if ra > max {
+		return max
+	}
+	return ra
+}
@@ -0,0 +1,32 @@
+package services
+
+import "testing"
+
+func TestAdaptiveReadahead(t *testing.T) {
+	const (
+		min = 2 << 20
+		max = 64 << 20
+	)
+	tests := []struct {
+		name      string
+		bitrate   float64
+		swarmRate float64
+		want      int64
+	}{
+		{"idle consumer", 0, 1 << 20, min},
+		{"swarm keeps up", 256 << 10, 256 << 10, 30 * 256 << 10},
+		{"slow swarm grows", 256 << 10, 128 << 10, 2 * 30 * 256 << 10},
+		{"stalled swarm grows to max factor", 256 << 10, 0, 4 * 30 * 256 << 10},
+		{"fast swarm shrinks", 256 << 10, 10 << 20, 30 * 256 << 10 / 2},
+		{"bounded by min", 16 << 10, 10 << 20, min},
+		{"bounded by max", 4 << 20, 1 << 20, max},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := adaptiveReadahead(tt.bitrate, tt.swarmRate, min, max)
+			if got != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	stallStageFirstByte = "first_byte"
+	stallStageChunk     = "chunk"
+	stallRetryAfter     = 10 * time.Second
+)
+
+var ErrStalled = errors.New("torrent read stalled")
+
+var (
+	promStallAborts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "torrent_web_seeder_stall_aborts_total",
+		Help: "Total number of requests aborted because requested pieces were not arriving",
+	}, []string{"stage"})
+)
+
+func init() {
+	prometheus.MustRegister(promStallAborts)
+}
+
+// StallReader reads torrent file with deadlines for the first byte and for
+// every following chunk. Once a deadline is hit all further reads fail, so
+// http.ServeContent gives up instead of hanging on an empty swarm.
+type StallReader struct {
+	torrent.Reader
+	t         *torrent.Torrent
+	f         *torrent.File
+	ctx       context.Context
+	firstByte time.Duration
+	chunk     time.Duration
+	pos       int64
+	started   bool
+	stage     string
+	err       error
+}
+
+func NewStallReader(ctx context.Context, t *torrent.Torrent, f *torrent.File, r torrent.Reader, firstByte time.Duration, chunk time.Duration) *StallReader {
+	return &StallReader{
+		Reader:    r,
+		t:         t,
+		f:         f,
+		ctx:       ctx,
+		firstByte: firstByte,
+		chunk:     chunk,
+	}
+}
+
+func (s *StallReader) Read(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	stage, timeout := stallStageChunk, s.chunk
+	if !s.started {
+		stage, timeout = stallStageFirstByte, s.firstByte
+	}
+	ctx := s.ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(s.ctx, timeout)
+		defer cancel()
+	}
+	n, err := s.Reader.ReadContext(ctx, p)
+	s.pos += int64(n)
+	if n > 0 {
+		s.started = true
+	}
+	if err != nil && n == 0 && s.ctx.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		s.stage = stage
+		s.err = errors.Wrapf(ErrStalled, "stage=%v timeout=%v", stage, timeout)
+		return 0, s.err
+	}
+	return n, err
+}
+
+func (s *StallReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := s.Reader.Seek(offset, whence)
+	if err == nil {
+		s.pos = pos
+	}
+	return pos, err
+}
+
+// Err returns ErrStalled if reading was aborted by deadline.
+func (s *StallReader) Err() error {
+	return s.err
+}
+
+// Stage returns at which stage reading stalled.
+func (s *StallReader) Stage() string {
+	return s.stage
+}
+
+type StallPieces struct {
+	First     int `json:"first"`
+	Last      int `json:"last"`
+	Complete  int `json:"complete"`
+	Available int `json:"available"`
+}
+
+// StallReport describes swarm state for the range a stalled request waited for.
+type StallReport struct {
+	Error      string      `json:"error"`
+	Stage      string      `json:"stage"`
+	RetryAfter int         `json:"retry_after"`
+	Peers      int         `json:"peers"`
+	Seeders    int         `json:"seeders"`
+	Offset     int64       `json:"offset"`
+	Pieces     StallPieces `json:"pieces"`
+}
+
+// Report builds StallReport for pieces from the stalled position to the end
+// of the requested range. Pieces are counted as available when at least one
+// connected peer has them.
+func (s *StallReader) Report(end int64) *StallReport {
+	stats := s.t.Stats()
+	if end <= s.pos || end > s.f.Length() {
+		end = s.f.Length()
+	}
+	pl := s.t.Info().PieceLength
+	first := int((s.f.Offset() + s.pos) / pl)
+	last := first
+	if end > s.pos {
+		last = int((s.f.Offset() + end - 1) / pl)
+	}
+	ps := StallPieces{First: first, Last: last}
+	conns := s.t.PeerConns()
+	for i := first; i <= last && i < s.t.NumPieces(); i++ {
+		if s.t.Piece(i).State().Complete {
+			ps.Complete++
+			ps.Available++
+			continue
+		}
+		for _, pc := range conns {
+			if pc.PeerPieces().Contains(uint32(i)) {
+				ps.Available++
+				break
+			}
+		}
+	}
+	return &StallReport{
+		Error:      "requested pieces are not arriving",
+		Stage:      s.stage,
+		RetryAfter: int(stallRetryAfter.Seconds()),
+		Peers:      stats.ActivePeers,
+		Seeders:    stats.ConnectedSeeders,
+		Offset:     s.pos,
+		Pieces:     ps,
+	}
+}
+
+// requestedRangeEnd returns exclusive end of the first range of Range header
+// or -1 if it is absent or open-ended.
+func requestedRangeEnd(r *http.Request) int64 {
+	spec, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes=")
+	if !ok {
+		return -1
+	}
+	spec, _, _ = strings.Cut(spec, ",")
+	start, end, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok || start == "" {
+		return -1
+	}
+	e, err := strconv.ParseInt(end, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return e + 1
+}
+
+// HeaderDeferringWriter holds back the status line until the first body
+// write, so a request that fails before sending any content can still be
+// answered with a different status.
+type HeaderDeferringWriter struct {
+	http.ResponseWriter
+	code      int
+	committed bool
+}
+
+func NewHeaderDeferringWriter(w http.ResponseWriter) *HeaderDeferringWriter {
+	return &HeaderDeferringWriter{ResponseWriter: w}
+}
+
+func (w *HeaderDeferringWriter) WriteHeader(statusCode int) {
+	if w.committed || w.code != 0 {
+		return
+	}
+	w.code = statusCode
+}
+
+func (w *HeaderDeferringWriter) Write(p []byte) (int, error) {
+	w.Commit()
+	return w.ResponseWriter.Write(p)
+}
+
+// Commit sends deferred status line, if any.
+func (w *HeaderDeferringWriter) Commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+	if w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+}
+
+// Committed reports whether status line was already sent to the client.
+func (w *HeaderDeferringWriter) Committed() bool {
+	return w.committed
+}
+
+func (w *HeaderDeferringWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("type assertion failed http.ResponseWriter not a http.Hijacker")
+	}
+	w.committed = true
+	return h.Hijack()
+}
+
+func (w *HeaderDeferringWriter) Flush() {
+	f, ok := w.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+	w.Commit()
+	f.Flush()
+}
+
+// Check interface implementations.
+var (
+	_ http.ResponseWriter = &HeaderDeferringWriter{}
+	_ http.Hijacker       = &HeaderDeferringWriter{}
+	_ http.Flusher        = &HeaderDeferringWriter{}
+)
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// fakeTorrentReader returns chunks sent to data channel and blocks until
+// context is done otherwise.
+type fakeTorrentReader struct {
+	torrent.Reader
+	data    chan []byte
+	reading chan struct{}
+	size    int64
+	pos     int64
+}
+
+func newFakeTorrentReader(size int64) *fakeTorrentReader {
+	return &fakeTorrentReader{
+		data:    make(chan []byte, 10),
+		reading: make(chan struct{}, 1),
+		size:    size,
+	}
+}
+
+func (r *fakeTorrentReader) ReadContext(ctx context.Context, p []byte) (int, error) {
+	select {
+	case r.reading <- struct{}{}:
+	default:
+	}
+	select {
+	case b := <-r.data:
+		n := copy(p, b)
+		r.pos += int64(n)
+		return n, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func (r *fakeTorrentReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+func (r *fakeTorrentReader) Close() error {
+	return nil
+}
+
+func serveStallContent(w http.ResponseWriter, sr *StallReader) *HeaderDeferringWriter {
+	dw := NewHeaderDeferringWriter(w)
+	dw.Header().Set("Content-Type", "video/x-matroska")
+	http.ServeContent(dw, httptest.NewRequest(http.MethodGet, "/", nil), "a.mkv", time.Unix(0, 0), sr)
+	return dw
+}
+
+func TestStallReader_FirstByteTimeout(t *testing.T) {
+	sr := NewStallReader(context.Background(), nil, nil, newFakeTorrentReader(10), 50*time.Millisecond, time.Second)
+	rec := httptest.NewRecorder()
+	dw := serveStallContent(rec, sr)
+
+	if !errors.Is(sr.Err(), ErrStalled) || sr.Stage() != stallStageFirstByte {
+		t.Fatalf("expected first byte stall, got err=%v stage=%v", sr.Err(), sr.Stage())
+	}
+	if dw.Committed() {
+		t.Fatal("expected status line not sent")
+	}
+	(&WebSeeder{}).renderStall(rec, &StallReport{Stage: sr.Stage(), RetryAfter: 10})
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %v", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "10" {
+		t.Fatalf("expected Retry-After 10, got %q", rec.Header().Get("Retry-After"))
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Fatal("expected Content-Length of file removed")
+	}
+	var rep StallReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &rep); err != nil || rep.Stage != stallStageFirstByte {
+		t.Fatalf("unexpected stall report %+v %v", rep, err)
+	}
+}
+
+func TestStallReader_ChunkStall(t *testing.T) {
+	r := newFakeTorrentReader(10)
+	r.data <- []byte("abcd")
+	sr := NewStallReader(context.Background(), nil, nil, r, time.Second, 50*time.Millisecond)
+	rec := httptest.NewRecorder()
+	dw := serveStallContent(rec, sr)
+
+	if !errors.Is(sr.Err(), ErrStalled) || sr.Stage() != stallStageChunk {
+		t.Fatalf("expected chunk stall, got err=%v stage=%v", sr.Err(), sr.Stage())
+	}
+	if !dw.Committed() || rec.Code != http.StatusOK {
+		t.Fatalf("expected response already started, got committed=%v code=%v", dw.Committed(), rec.Code)
+	}
+	if rec.Body.String() != "abcd" {
+		t.Fatalf("expected partial body, got %q", rec.Body.String())
+	}
+	if _, err := sr.Read(make([]byte, 1)); !errors.Is(err, ErrStalled) {
+		t.Fatalf("expected reads to keep failing after stall, got %v", err)
+	}
+}
+
+// headerRecorder records status line written by handler.
+type headerRecorder struct {
+	*httptest.ResponseRecorder
+	mux   sync.Mutex
+	wrote bool
+}
+
+func (w *headerRecorder) WriteHeader(code int) {
+	w.mux.Lock()
+	w.wrote = true
+	w.mux.Unlock()
+	w.ResponseRecorder.WriteHeader(code)
+}
+
+func (w *headerRecorder) Write(p []byte) (int, error) {
+	w.mux.Lock()
+	w.wrote = true
+	w.mux.Unlock()
+	return w.ResponseRecorder.Write(p)
+}
+
+func (w *headerRecorder) Wrote() bool {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return w.wrote
+}
+
+func TestHeaderDeferringWriter_WaitsForFirstByte(t *testing.T) {
+	r := newFakeTorrentReader(4)
+	sr := NewStallReader(context.Background(), nil, nil, r, time.Second, time.Second)
+	rec := &headerRecorder{ResponseRecorder: httptest.NewRecorder()}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serveStallContent(rec, sr)
+	}()
+
+	<-r.reading
+	if rec.Wrote() {
+		t.Fatal("expected headers not sent before first byte")
+	}
+	r.data <- []byte("abcd")
+	<-done
+	if sr.Err() != nil {
+		t.Fatal(sr.Err())
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "abcd" {
+		t.Fatalf("unexpected response code=%v body=%q", rec.Code, rec.Body.String())
+	}
+}
@@ -15,19 +15,26 @@ import (
 	"github.com/anacrolix/torrent"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
 	"github.com/webtor-io/lazymap"
 	pb "github.com/webtor-io/torrent-web-seeder/proto"
 )
 
 type Stat struct {
 	pb.UnimplementedTorrentWebSeederServer
-	tm    *TorrentMap
-	cache lazymap.LazyMap[*pb.StatReply]
+	tm         *TorrentMap
+	pf         *Prefetcher
+	jq         *JobQueue
+	cache      lazymap.LazyMap[*pb.StatReply]
+	adminToken string
 }
 
-func NewStat(tm *TorrentMap) *Stat {
+func NewStat(c *cli.Context, tm *TorrentMap, pf *Prefetcher, jq *JobQueue) *Stat {
 	return &Stat{
-		tm: tm,
+		tm:         tm,
+		pf:         pf,
+		jq:         jq,
+		adminToken: c.String(AdminTokenFlag),
 		cache: lazymap.New[*pb.StatReply](&lazymap.Config{
 			Expire:      3 * time.Second,
 			StoreErrors: true,
@@ -46,6 +53,16 @@ func fileBytesCompleted(f *torrent.File) int64 {
 	return res
 }
 
+// webseedBytesRead returns useful bytes downloaded from web seeds of the torrent.
+func webseedBytesRead(t *torrent.Torrent) int64 {
+	var res int64
+	for _, p := range t.WebseedPeerConns() {
+		stats := p.Stats()
+		res += stats.BytesReadUsefulData.Int64()
+	}
+	return res
+}
+
 func (s *Stat) torrentStat(t *torrent.Torrent) (*pb.StatReply, error) {
 	completed := t.BytesCompleted()
 	rStatus := pb.StatReply_SEEDING
@@ -77,6 +94,8 @@ func (s *Stat) torrentStat(t *torrent.Torrent) (*pb.StatReply, error) {
 		Seeders:   int32(seeders),
 		Leechers:  int32(leechers),
 		Pieces:    pieces,
+
+		WebseedBytes: webseedBytesRead(t),
 	}, nil
 }
 
@@ -109,9 +128,32 @@ func (s *Stat) fileStat(t *torrent.Torrent, f *torrent.File) (*pb.StatReply, err
 		Seeders:   int32(seeders),
 		Leechers:  int32(leechers),
 		Pieces:    pieces,
+		Priority:  filePriorityReply(s.tm.FilePriority(t.InfoHash().HexString(), f.Path())),
+
+		WebseedBytes: webseedBytesRead(t),
 	}, nil
 }
 
+var filePriorities = map[FilePriority]pb.File_Priority{
+	FilePriorityDefault: pb.File_DEFAULT,
+	FilePrioritySkip:    pb.File_SKIP,
+	FilePriorityNormal:  pb.File_NORMAL,
+	FilePriorityHigh:    pb.File_HIGH,
+}
+
+func filePriorityReply(p FilePriority) pb.File_Priority {
+	return filePriorities[p]
+}
+
+func filePriorityOf(p pb.File_Priority) (FilePriority, error) {
+	for fp, pp := range filePriorities {
+		if pp == p {
+			return fp, nil
+		}
+	}
+	return "", errors.Errorf("invalid file priority %v", p)
+}
+
 func findFile(t *torrent.Torrent, path string) *torrent.File {
 	for _, f := range t.Files() {
 		if f.Path() == path {
@@ -195,7 +237,9 @@ func (s *Stat) StatStream(in *pb.StatRequest, stream pb.TorrentWebSeeder_StatStr
 			}
 			if prevRep == nil ||
 				rep.GetCompleted() != prevRep.GetCompleted() ||
-				rep.GetPeers() != prevRep.GetPeers() {
+				rep.GetPeers() != prevRep.GetPeers() ||
+				rep.GetWebseedBytes() != prevRep.GetWebseedBytes() ||
+				rep.GetPriority() != prevRep.GetPriority() {
 				var diffPieces []*pb.Piece
 				if prevRep == nil {
 					diffPieces = rep.GetPieces()
@@ -204,11 +248,13 @@ func (s *Stat) StatStream(in *pb.StatRequest, stream pb.TorrentWebSeeder_StatStr
 				}
 				prevRep = rep
 				diffRep := &pb.StatReply{
-					Completed: rep.GetCompleted(),
-					Peers:     rep.GetPeers(),
-					Status:    rep.GetStatus(),
-					Total:     rep.GetTotal(),
-					Pieces:    diffPieces,
+					Completed:    rep.GetCompleted(),
+					Peers:        rep.GetPeers(),
+					Status:       rep.GetStatus(),
+					Total:        rep.GetTotal(),
+					Pieces:       diffPieces,
+					WebseedBytes: rep.GetWebseedBytes(),
+					Priority:     rep.GetPriority(),
 				}
 				if err := stream.Send(diffRep); err != nil {
 					log.WithError(err).Error("failed to send stat")
@@ -273,7 +319,200 @@ func (s *Stat) Files(ctx context.Context, _ *pb.FilesRequest) (*pb.FilesReply, e
 	}
 	var fs []*pb.File
 	for _, f := range t.Files() {
-		fs = append(fs, &pb.File{Path: f.Path()})
+		fs = append(fs, &pb.File{Path: f.Path(), Priority: filePriorityReply(s.tm.FilePriority(h, f.Path()))})
 	}
 	return &pb.FilesReply{Files: fs}, nil
 }
+
+func (s *Stat) AddMagnet(ctx context.Context, in *pb.AddMagnetRequest) (*pb.AddMagnetReply, error) {
+	if in.GetMagnet() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "no magnet provided")
+	}
+	t, err := s.tm.AddMagnet(ctx, in.GetMagnet())
+	if errors.Is(err, ErrMagnetTimeout) {
+		return nil, status.Errorf(codes.DeadlineExceeded, "got error=%v", err)
+	} else if err != nil {
+		return nil, err
+	}
+	var fs []*pb.File
+	for _, f := range t.Files() {
+		fs = append(fs, &pb.File{Path: f.Path()})
+	}
+	return &pb.AddMagnetReply{
+		InfoHash: t.InfoHash().HexString(),
+		Name:     t.Name(),
+		Files:    fs,
+	}, nil
+}
+
+// checkAdmin authorizes admin grpc methods with admin-token metadata.
+// Unlike admin http api they share the port with public methods, so they
+// are disabled unless admin token is configured.
+func (s *Stat) checkAdmin(ctx context.Context) error {
+	if s.adminToken == "" {
+		return status.Errorf(codes.PermissionDenied, "admin api is disabled")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if v := md.Get("admin-token"); len(v) > 0 {
+		token = v[0]
+	}
+	if !checkAdminToken(s.adminToken, token, false) {
+		return status.Errorf(codes.Unauthenticated, "invalid admin token")
+	}
+	return nil
+}
+
+func (s *Stat) ListTorrents(ctx context.Context, _ *pb.ListTorrentsRequest) (*pb.ListTorrentsReply, error) {
+	err := s.checkAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	active := s.tm.Active()
+	ts := make([]*pb.ActiveTorrent, 0, len(active))
+	for _, at := range active {
+		ts = append(ts, &pb.ActiveTorrent{
+			InfoHash:    at.InfoHash,
+			Name:        at.Name,
+			AddedAt:     at.Added.Unix(),
+			LastTouch:   at.LastTouch.Unix(),
+			BytesCached: at.BytesCached,
+			Peers:       int32(at.Peers),
+			Pinned:      at.Pinned,
+			Ttl:         int64(at.TTL),
+			Readers:     int32(at.Readers),
+		})
+	}
+	return &pb.ListTorrentsReply{Torrents: ts}, nil
+}
+
+func (s *Stat) DropTorrent(ctx context.Context, in *pb.DropTorrentRequest) (*pb.DropTorrentReply, error) {
+	err := s.checkAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !s.tm.Drop(in.GetInfoHash()) {
+		return nil, status.Errorf(codes.NotFound, "torrent is not active infohash=%v", in.GetInfoHash())
+	}
+	return &pb.DropTorrentReply{}, nil
+}
+
+func (s *Stat) PinTorrent(ctx context.Context, in *pb.PinTorrentRequest) (*pb.PinTorrentReply, error) {
+	err := s.checkAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !s.tm.Pin(in.GetInfoHash(), in.GetPinned()) {
+		return nil, status.Errorf(codes.NotFound, "torrent is not active infohash=%v", in.GetInfoHash())
+	}
+	return &pb.PinTorrentReply{}, nil
+}
+
+func (s *Stat) SetFilePriority(ctx context.Context, in *pb.SetFilePriorityRequest) (*pb.SetFilePriorityReply, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("info-hash")) == 0 || md.Get("info-hash")[0] == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "no info-hash provided")
+	}
+	h := md.Get("info-hash")[0]
+	p, err := filePriorityOf(in.GetPriority())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err = s.tm.SetFilePriority(ctx, h, in.GetPath(), p)
+	if errors.Is(err, ErrTorrentNotFound) || errors.Is(err, ErrFileNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	return &pb.SetFilePriorityReply{}, nil
+}
+
+func (s *Stat) Prefetch(ctx context.Context, in *pb.PrefetchRequest) (*pb.PrefetchReply, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("info-hash")) == 0 || md.Get("info-hash")[0] == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "no info-hash provided")
+	}
+	h := md.Get("info-hash")[0]
+	var explicit *mediaRange
+	if in.GetEnd() > 0 {
+		if in.GetStart() < 0 || in.GetEnd() <= in.GetStart() {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid range start=%v end=%v", in.GetStart(), in.GetEnd())
+		}
+		explicit = &mediaRange{start: in.GetStart(), end: in.GetEnd()}
+	}
+	res, err := s.pf.Prefetch(ctx, h, in.GetPath(), in.GetSize(), explicit)
+	if errors.Is(err, ErrTorrentNotFound) || errors.Is(err, ErrFileNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	return &pb.PrefetchReply{
+		Pieces:     int32(res.Pieces),
+		Complete:   int32(res.Complete),
+		KeepAlive:  int64(res.KeepAlive),
+		FirstPiece: int32(res.FirstPiece),
+		LastPiece:  int32(res.LastPiece),
+	}, nil
+}
+
+func jobReply(j Job) *pb.Job {
+	res := &pb.Job{
+		Id:             j.ID,
+		InfoHash:       j.InfoHash,
+		Path:           j.Path,
+		Priority:       int32(j.Priority),
+		State:          j.State,
+		Error:          j.Error,
+		Files:          int32(j.Files),
+		CompletedFiles: int32(j.CompletedFiles),
+		Bytes:          j.Bytes,
+		CompletedBytes: j.CompletedBytes,
+		CreatedAt:      j.Created.Unix(),
+	}
+	if !j.Started.IsZero() {
+		res.StartedAt = j.Started.Unix()
+	}
+	if !j.Finished.IsZero() {
+		res.FinishedAt = j.Finished.Unix()
+	}
+	return res
+}
+
+func (s *Stat) SubmitJob(ctx context.Context, in *pb.SubmitJobRequest) (*pb.SubmitJobReply, error) {
+	err := s.checkAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	j, err := s.jq.Submit(in.GetInfoHash(), in.GetPath(), int(in.GetPriority()))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.SubmitJobReply{Job: jobReply(j)}, nil
+}
+
+func (s *Stat) CancelJob(ctx context.Context, in *pb.CancelJobRequest) (*pb.CancelJobReply, error) {
+	err := s.checkAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = s.jq.Cancel(in.GetId())
+	if errors.Is(err, ErrJobNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	} else if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.CancelJobReply{}, nil
+}
+
+func (s *Stat) ListJobs(ctx context.Context, _ *pb.ListJobsRequest) (*pb.ListJobsReply, error) {
+	err := s.checkAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jobs := s.jq.List()
+	res := make([]*pb.Job, 0, len(jobs))
+	for _, j := range jobs {
+		res = append(res, jobReply(j))
+	}
+	return &pb.ListJobsReply{Jobs: res}, nil
+}
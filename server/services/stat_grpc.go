@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
@@ -9,6 +10,9 @@ import (
 	"github.com/urfave/cli"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"google.golang.org/grpc/reflection"
 
@@ -88,7 +92,10 @@ func (ss *StatGRPC) Close() {
 
 func (ss *StatGRPC) get() (*grpc.Server, error) {
 	log.Info("initializing Stat")
-	s := grpc.NewServer()
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(torrentUnaryInterceptor),
+		grpc.StreamInterceptor(torrentStreamInterceptor),
+	)
 	pb.RegisterTorrentWebSeederServer(s, ss.st)
 	reflection.Register(s)
 	return s, nil
@@ -100,3 +107,52 @@ func (s *StatGRPC) Get() (*grpc.Server, error) {
 	})
 	return s.s, s.err
 }
+
+// torrentTTLContext applies keep-alive window requested with torrent-ttl metadata.
+func torrentTTLContext(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	v := md.Get("torrent-ttl")
+	if len(v) == 0 || v[0] == "" {
+		return ctx, nil
+	}
+	ttl, err := ParseTorrentTTL(v[0])
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return WithTorrentTTL(ctx, ttl), nil
+}
+
+// torrentStatusError reports exhausted active torrents limit or disk space
+// with proper grpc code.
+func torrentStatusError(err error) error {
+	if errors.Is(err, ErrTooManyTorrents) || errors.Is(err, ErrLowDiskSpace) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return err
+}
+
+func torrentUnaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := torrentTTLContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := handler(ctx, req)
+	return res, torrentStatusError(err)
+}
+
+type torrentTTLStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *torrentTTLStream) Context() context.Context {
+	return s.ctx
+}
+
+func torrentStreamInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := torrentTTLContext(ss.Context())
+	if err != nil {
+		return err
+	}
+	return torrentStatusError(handler(srv, &torrentTTLStream{ServerStream: ss, ctx: ctx}))
+}
@@ -0,0 +1,616 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	stdlog "log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/missinggo/v2"
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	StorageFlag       = "storage"
+	StorageMMap       = "mmap"
+	StorageFile       = "file"
+	evictedEventDelay = time.Second
+)
+
+func RegisterStorageFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.StringFlag{
+			Name:   StorageFlag,
+			Usage:  "torrent data storage backend (mmap or file)",
+			Value:  StorageMMap,
+			EnvVar: "STORAGE",
+		},
+	)
+}
+
+// torrentData is content of a torrent laid out as concatenation of its files.
+type torrentData interface {
+	io.ReaderAt
+	io.WriterAt
+	Flush() error
+	// Files returns content files in torrent order, used to punch holes.
+	Files() []*os.File
+	// DropPages advises kernel to release cached pages of the range.
+	DropPages(off int64, length int64)
+	Close() error
+}
+
+// dataOpener opens content files of a torrent in dir.
+type dataOpener func(info *metainfo.Info, dir string) (torrentData, error)
+
+type storageClientImpl struct {
+	open    dataOpener
+	baseDir string
+	budget  int64
+	global  *CacheBudget
+	disk    *DiskMonitor
+	wh      *Webhooks
+	cl      *torrent.Client // set after torrent.NewClient(), used for eviction VerifyData
+
+	mux         sync.Mutex
+	completions map[metainfo.Hash]*pieceCompletion // piece completions of open torrents
+}
+
+// NewStorage creates storage backend of given kind ("mmap" or "file").
+// budget is the per-torrent cache budget in bytes (0 = unlimited, no eviction).
+// global is the node-wide budget shared by all torrents (nil = no global eviction).
+// disk evicts pieces when free disk space runs low (nil = disabled).
+// wh receives completion and eviction events (nil = disabled).
+func NewStorage(kind string, baseDir string, budget int64, global *CacheBudget, disk *DiskMonitor, wh *Webhooks) (*storageClientImpl, error) {
+	var open dataOpener
+	switch kind {
+	case StorageMMap:
+		open = openMMapData
+	case StorageFile:
+		open = openFileData
+	default:
+		return nil, errors.Errorf("unknown storage %q", kind)
+	}
+	if budget > 0 {
+		promCacheBudget.Set(float64(budget))
+	}
+	return &storageClientImpl{
+		open:        open,
+		baseDir:     baseDir,
+		budget:      budget,
+		global:      global,
+		disk:        disk,
+		wh:          wh,
+		completions: map[metainfo.Hash]*pieceCompletion{},
+	}, nil
+}
+
+// SetClient wires the torrent client reference for piece eviction.
+// Called once after torrent.NewClient().
+func (s *storageClientImpl) SetClient(cl *torrent.Client) {
+	s.cl = cl
+}
+
+func (s *storageClientImpl) OpenTorrent(_ context.Context, info *metainfo.Info, infoHash metainfo.Hash) (_ storage.TorrentImpl, err error) {
+	dir, err := GetDir(s.baseDir, infoHash.HexString())
+	if err != nil {
+		return
+	}
+	data, err := s.open(info, dir)
+	if err != nil {
+		return
+	}
+	pc := pieceCompletionForDir(dir, info, infoHash, s.wh)
+
+	// Only enable per-torrent LRU eviction if the torrent is larger than the cache budget.
+	// Small torrents fit entirely in their budget, but are still evicted when the
+	// global budget is exceeded or the disk is running out of free space.
+	perTorrentEviction := s.budget > 0 && info.TotalLength() > s.budget
+	evictionEnabled := perTorrentEviction || s.global != nil || s.disk != nil
+
+	var fileLens []int64
+	for _, f := range info.UpvertedFiles() {
+		fileLens = append(fileLens, f.Length)
+	}
+	t := &torrentStorage{
+		infoHash:  infoHash,
+		data:      data,
+		pc:        pc,
+		info:      info,
+		fileLens:  fileLens,
+		closeCh:   make(chan struct{}),
+		cl:        s.cl,
+		global:    s.global,
+		disk:      s.disk,
+		wh:        s.wh,
+		dropPages: perTorrentEviction,
+	}
+	if pci, ok := pc.(*pieceCompletion); ok {
+		s.trackCompletion(infoHash, pci, t.closeCh)
+	}
+
+	if evictionEnabled {
+		var budget int64
+		if perTorrentEviction {
+			budget = s.budget
+		}
+		lru := NewPieceLRU(budget)
+		// Protect pieces belonging to completed files from eviction (first pass).
+		if pci, ok := pc.(*pieceCompletion); ok {
+			lru.SetProtectedFunc(func(index int) bool {
+				return pci.completions.IsPieceInCompletedFile(index)
+			})
+		}
+		recoverLRU(lru, pc, info, infoHash)
+		t.lru = lru
+		t.verifyCh = make(chan int, 256)
+
+		if budget > 0 && lru.Used() > budget {
+			log.Infof("cache over budget on recovery (%d > %d), evicting", lru.Used(), budget)
+			t.evictOverBudget()
+		}
+		if s.global != nil {
+			s.global.Register(lru, t.evictPiece)
+			s.global.Enforce()
+		}
+		if s.disk != nil {
+			s.disk.Register(dir, lru, t.evictPiece)
+		}
+		t.startEvictionSweep()
+		log.Infof("eviction enabled for torrent %s (size=%d, budget=%d)",
+			infoHash.HexString(), info.TotalLength(), budget)
+	}
+
+	impl := storage.TorrentImpl{
+		Piece: t.Piece,
+		Close: t.Close,
+	}
+	// Note: we intentionally do NOT set impl.Capacity here.
+	// TorrentCapacity with RemainingBudget=0 causes anacrolix to stop requesting
+	// pieces entirely, which hangs downloads. Eviction is enforced synchronously
+	// in MarkComplete and via background sweep — Capacity is not needed.
+	return impl, nil
+}
+
+// trackCompletion keeps piece completion of torrent until its storage is
+// closed, so file completion of open torrent is read without opening its db.
+func (s *storageClientImpl) trackCompletion(h metainfo.Hash, pc *pieceCompletion, closeCh chan struct{}) {
+	s.mux.Lock()
+	s.completions[h] = pc
+	s.mux.Unlock()
+	go func() {
+		<-closeCh
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		if s.completions[h] == pc {
+			delete(s.completions, h)
+		}
+	}()
+}
+
+// completion returns piece completion of open torrent h, nil if torrent is
+// not open.
+func (s *storageClientImpl) completion(h metainfo.Hash) *pieceCompletion {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.completions[h]
+}
+
+// recoverLRU populates the LRU tracker with pieces already marked complete in SQLite.
+func recoverLRU(lru *PieceLRU, pc storage.PieceCompletion, info *metainfo.Info, infoHash metainfo.Hash) {
+	completePieces := make(map[int]int64)
+	for i := 0; i < info.NumPieces(); i++ {
+		pk := metainfo.PieceKey{InfoHash: infoHash, Index: i}
+		c, err := pc.Get(pk)
+		if err != nil {
+			continue
+		}
+		if c.Ok && c.Complete {
+			completePieces[i] = info.Piece(i).Length()
+		}
+	}
+	if len(completePieces) > 0 {
+		lru.Recover(completePieces)
+		log.Infof("recovered %d complete pieces (%d bytes) for LRU",
+			len(completePieces), lru.Used())
+		promCacheBytesUsed.Add(float64(lru.Used()))
+		promCachePieceCount.Add(float64(len(completePieces)))
+	}
+}
+
+func (s *storageClientImpl) Close() error {
+	return nil
+}
+
+type torrentStorage struct {
+	infoHash metainfo.Hash
+	data     torrentData
+	pc       storage.PieceCompletion
+	lru      *PieceLRU
+	info     *metainfo.Info
+	fileLens []int64 // file lengths for piece→file mapping
+	closeCh  chan struct{}
+	cl       *torrent.Client // for VerifyData on eviction
+	verifyCh chan int        // evicted piece indices queued for VerifyData
+	global   *CacheBudget    // node-wide budget, nil if disabled
+	disk     *DiskMonitor    // free disk space monitor, nil if disabled
+	wh       *Webhooks       // event webhooks, nil if disabled
+	// drop page cache of read and completed pieces, only torrents over
+	// per-torrent budget do it
+	dropPages bool
+
+	evictedMu    sync.Mutex
+	evicted      []int       // evicted pieces not reported to webhooks yet
+	evictedTimer *time.Timer // flushes evicted pieces
+}
+
+func (ts *torrentStorage) Piece(p metainfo.Piece) storage.PieceImpl {
+	return storagePiece{
+		t:             ts,
+		p:             p,
+		sectionReader: io.NewSectionReader(ts.data, p.Offset(), p.Length()),
+		sectionWriter: missinggo.NewSectionWriter(ts.data, p.Offset(), p.Length()),
+	}
+}
+
+// startEvictionSweep runs a periodic background eviction sweep.
+// Also processes the verify queue — calling VerifyData on evicted pieces
+// to notify anacrolix that they need re-downloading.
+func (ts *torrentStorage) startEvictionSweep() {
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ts.closeCh:
+				return
+			case idx := <-ts.verifyCh:
+				ts.verifyPiece(idx)
+			case <-ticker.C:
+				if ts.lru.Used() > ts.lru.budget && ts.lru.budget > 0 {
+					ts.evictOverBudget()
+				}
+				if ts.global != nil {
+					ts.global.Enforce()
+				}
+			}
+		}
+	}()
+}
+
+// verifyPiece calls VerifyData on a piece to notify anacrolix that it's no longer valid.
+func (ts *torrentStorage) verifyPiece(idx int) {
+	if ts.cl == nil {
+		return
+	}
+	for _, t := range ts.cl.Torrents() {
+		if t.InfoHash() == ts.infoHash {
+			t.Piece(idx).VerifyData()
+			return
+		}
+	}
+}
+
+func (ts *torrentStorage) Close() error {
+	close(ts.closeCh)
+	if ts.global != nil && ts.lru != nil {
+		ts.global.Unregister(ts.lru)
+	}
+	if ts.disk != nil && ts.lru != nil {
+		ts.disk.Unregister(ts.lru)
+	}
+	if ts.lru != nil {
+		promCacheBytesUsed.Sub(float64(ts.lru.Used()))
+		ts.lru.mu.Lock()
+		promCachePieceCount.Sub(float64(len(ts.lru.entries)))
+		ts.lru.mu.Unlock()
+	}
+	ts.flushEvicted()
+	// Drop all cached pages before closing. This ensures immediate RSS
+	// release when a torrent is dropped, rather than waiting for the kernel
+	// to lazily reclaim pages.
+	ts.data.DropPages(0, ts.info.TotalLength())
+	if err := ts.pc.Close(); err != nil {
+		log.WithError(err).Warn("failed to close piece completion")
+	}
+	return ts.data.Close()
+}
+
+type storagePiece struct {
+	t             *torrentStorage
+	p             metainfo.Piece
+	sectionReader *io.SectionReader
+	sectionWriter *missinggo.SectionWriter
+}
+
+func (me storagePiece) ReadAt(b []byte, off int64) (int, error) {
+	if me.t.lru != nil {
+		me.t.lru.Touch(me.p.Index())
+	}
+	n, err := me.sectionReader.ReadAt(b, off)
+	// After copying data into the buffer, advise the kernel to drop the
+	// pages. The data is now in `b` and will be sent to the client;
+	// keeping it in the page cache wastes cgroup memory.
+	if n > 0 && me.t.dropPages {
+		me.t.data.DropPages(me.p.Offset()+off, int64(n))
+	}
+	return n, err
+}
+
+func (me storagePiece) WriteAt(b []byte, off int64) (int, error) {
+	return me.sectionWriter.WriteAt(b, off)
+}
+
+func (me storagePiece) Flush() error {
+	return me.t.data.Flush()
+}
+
+func (me storagePiece) pieceKey() metainfo.PieceKey {
+	return metainfo.PieceKey{InfoHash: me.t.infoHash, Index: me.p.Index()}
+}
+
+func (sp storagePiece) Completion() storage.Completion {
+	c, err := sp.t.pc.Get(sp.pieceKey())
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (sp storagePiece) MarkComplete() error {
+	err := sp.t.pc.Set(sp.pieceKey(), true)
+	if err != nil {
+		return err
+	}
+	// The piece has been fully written and verified. Drop its pages —
+	// dirty pages will be written back by the kernel asynchronously, and
+	// clean pages are freed immediately. This prevents downloaded pieces
+	// from accumulating in cgroup memory.
+	if sp.t.dropPages {
+		sp.t.data.DropPages(sp.p.Offset(), sp.p.Length())
+	}
+	if sp.t.lru != nil {
+		toEvict := sp.t.lru.Add(sp.p.Index(), sp.p.Length())
+		promCacheBytesUsed.Add(float64(sp.p.Length()))
+		promCachePieceCount.Inc()
+		for _, idx := range toEvict {
+			sp.t.evictPiece(idx)
+		}
+		if sp.t.global != nil {
+			sp.t.global.Enforce()
+		}
+	}
+	return nil
+}
+
+func (sp storagePiece) MarkNotComplete() error {
+	return sp.t.pc.Set(sp.pieceKey(), false)
+}
+
+// fileRegion describes a contiguous region within a single torrent file.
+type fileRegion struct {
+	fileIndex int
+	offset    int64
+	length    int64
+}
+
+// pieceFileRegions computes which file regions a piece covers.
+// A piece may span multiple files in a multi-file torrent.
+func (ts *torrentStorage) pieceFileRegions(p metainfo.Piece) []fileRegion {
+	return spanFileRegions(ts.fileLens, p.Offset(), p.Length())
+}
+
+// spanFileRegions computes which file regions a range of concatenated files
+// covers.
+func spanFileRegions(fileLens []int64, off int64, length int64) []fileRegion {
+	end := off + length
+	var regions []fileRegion
+	fileOff := int64(0)
+	for i, fLen := range fileLens {
+		fEnd := fileOff + fLen
+		if end > fileOff && off < fEnd {
+			regStart := max(off, fileOff) - fileOff
+			regLen := min(end, fEnd) - max(off, fileOff)
+			regions = append(regions, fileRegion{
+				fileIndex: i,
+				offset:    regStart,
+				length:    regLen,
+			})
+		}
+		if fileOff >= end {
+			break
+		}
+		fileOff = fEnd
+	}
+	return regions
+}
+
+// evictOverBudget runs eviction until cache usage is within budget.
+// Called once after recovery if the recovered state exceeds the budget.
+func (ts *torrentStorage) evictOverBudget() {
+	ts.lru.mu.Lock()
+	toEvict := ts.lru.computeEvictions()
+	ts.lru.mu.Unlock()
+	for _, idx := range toEvict {
+		ts.evictPiece(idx)
+	}
+}
+
+// evictPiece removes a piece from cache by punching holes in content files
+// and marking it as incomplete.
+func (ts *torrentStorage) evictPiece(idx int) {
+	piece := ts.info.Piece(idx)
+	pk := metainfo.PieceKey{InfoHash: ts.infoHash, Index: idx}
+
+	// 1. Mark piece incomplete in SQLite + in-memory state.
+	if err := ts.pc.Set(pk, false); err != nil {
+		log.WithError(err).Errorf("failed to mark piece %d incomplete during eviction", idx)
+		return
+	}
+
+	// 2. Clean up file_completion for affected files.
+	ts.uncompleteAffectedFiles(idx)
+
+	// 3. Punch holes in content files to free disk blocks.
+	files := ts.data.Files()
+	for _, region := range ts.pieceFileRegions(piece) {
+		if region.fileIndex >= len(files) || files[region.fileIndex] == nil {
+			continue
+		}
+		if err := punchHole(files[region.fileIndex], region.offset, region.length); err != nil {
+			log.WithError(err).Errorf("failed to punch hole for piece %d in file %d", idx, region.fileIndex)
+		}
+	}
+	// Dropping pages ensures the kernel immediately reclaims them from the
+	// process's page tables and cgroup memory accounting, preventing stale
+	// page-table entries from being re-faulted by concurrent reads.
+	ts.data.DropPages(piece.Offset(), piece.Length())
+
+	// 4. Remove from LRU tracker and update metrics.
+	prevUsed := ts.lru.Used()
+	ts.lru.Remove(idx)
+	freedBytes := prevUsed - ts.lru.Used()
+	promCacheBytesUsed.Sub(float64(freedBytes))
+	promCachePieceCount.Dec()
+	promCacheEvictions.Inc()
+
+	log.Infof("evicted piece %d, freed %d bytes, used=%d budget=%d",
+		idx, freedBytes, ts.lru.Used(), ts.lru.budget)
+	ts.emitEvicted(idx)
+
+	// 5. Queue VerifyData to notify anacrolix that this piece is no longer valid.
+	// Done via channel to avoid calling VerifyData inside MarkComplete's call chain
+	// (which could deadlock on anacrolix internal locks).
+	select {
+	case ts.verifyCh <- idx:
+	default:
+		// Channel full — verify goroutine will catch up via background sweep.
+	}
+}
+
+// emitEvicted queues evicted piece for piece.evicted webhook event. Pieces
+// evicted within evictedEventDelay are reported in single event, so eviction
+// sweep does not flood webhook queues and push out completion events.
+func (ts *torrentStorage) emitEvicted(idx int) {
+	if ts.wh == nil {
+		return
+	}
+	ts.evictedMu.Lock()
+	defer ts.evictedMu.Unlock()
+	ts.evicted = append(ts.evicted, idx)
+	if ts.evictedTimer == nil {
+		ts.evictedTimer = time.AfterFunc(evictedEventDelay, ts.flushEvicted)
+	}
+}
+
+// flushEvicted emits piece.evicted event for queued pieces.
+func (ts *torrentStorage) flushEvicted() {
+	ts.evictedMu.Lock()
+	pieces := ts.evicted
+	ts.evicted = nil
+	if ts.evictedTimer != nil {
+		ts.evictedTimer.Stop()
+		ts.evictedTimer = nil
+	}
+	ts.evictedMu.Unlock()
+	if len(pieces) == 0 {
+		return
+	}
+	sort.Ints(pieces)
+	ts.wh.Emit(WebhookEvent{Type: EventPieceEvicted, InfoHash: ts.infoHash.HexString(), Pieces: pieces})
+}
+
+// uncompleteAffectedFiles finds files that include the given piece index
+// and removes them from the file_completion table.
+func (ts *torrentStorage) uncompleteAffectedFiles(pieceIndex int) {
+	var affectedFiles []string
+	if len(ts.info.Files) == 0 {
+		// Single-file torrent.
+		affectedFiles = append(affectedFiles, ts.info.Name)
+	} else {
+		offset := 0
+		for _, f := range ts.info.Files {
+			path := ts.info.Name + "/" + strings.Join(f.Path, "/")
+			startPiece := offset / int(ts.info.PieceLength)
+			endPiece := (offset + int(f.Length)) / int(ts.info.PieceLength)
+			offset += int(f.Length)
+			if pieceIndex >= startPiece && pieceIndex <= endPiece {
+				affectedFiles = append(affectedFiles, path)
+			}
+		}
+	}
+	if len(affectedFiles) == 0 {
+		return
+	}
+	type fileUncompleter interface {
+		UncompleteFiles(paths []string) error
+	}
+	if fu, ok := ts.pc.(fileUncompleter); ok {
+		if err := fu.UncompleteFiles(affectedFiles); err != nil {
+			log.WithError(err).Error("failed to uncomplete files during eviction")
+		}
+	}
+}
+
+// contentFilePath returns path of torrent file content in torrent data dir:
+// content/<sha1 prefix>/<sha1 of safe file path>.
+func contentFilePath(location string, md *metainfo.Info, miFile metainfo.FileInfo) (string, error) {
+	safeName, err := storage.ToSafeFilePath(append([]string{md.BestName()}, miFile.BestPath()...)...)
+	if err != nil {
+		return "", err
+	}
+	hash := sha1.Sum([]byte(safeName))
+	hexHash := fmt.Sprintf("%x", hash)
+	subPath := hexHash[:2]
+	return filepath.Join(location, "content", subPath, hexHash), nil
+}
+
+// openContentFile opens content file, creating it with given size if needed.
+func openContentFile(name string, size int64) (file *os.File, err error) {
+	dir := filepath.Dir(name)
+	err = os.MkdirAll(dir, 0o750)
+	if err != nil {
+		err = fmt.Errorf("making directory %q: %s", dir, err)
+		return
+	}
+	file, err = os.OpenFile(name, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			file = nil
+		}
+	}()
+	var fi os.FileInfo
+	fi, err = file.Stat()
+	if err != nil {
+		return
+	}
+	if fi.Size() < size {
+		err = file.Truncate(size)
+	}
+	return
+}
+
+func pieceCompletionForDir(dir string, info *metainfo.Info, hash metainfo.Hash, wh *Webhooks) (ret storage.PieceCompletion) {
+	ret, err := NewPieceCompletion(dir, info, hash, wh)
+	if err != nil {
+		stdlog.Printf("couldn't open piece completion db in %q: %s", dir, err)
+		ret = storage.NewMapPieceCompletion()
+	}
+	return
+}
@@ -13,6 +13,7 @@ import (
 	"code.cloudfoundry.org/bytefmt"
 	tlog "github.com/anacrolix/log"
 	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
@@ -102,7 +103,8 @@ func (m *metricsDialer) DialerNetwork() string {
 
 type TorrentClient struct {
 	cl                         *torrent.Client
-	storageImpl                *mmapClientImpl
+	storageImpl                *storageClientImpl
+	storage                    string
 	mux                        sync.Mutex
 	err                        error
 	inited                     bool
@@ -129,6 +131,9 @@ type TorrentClient struct {
 	pieceHashersPerTorrent     int
 	dialRateLimit              int
 	perTorrentCacheBudget      int64
+	cacheBudget                int64
+	disk                       *DiskMonitor
+	wh                         *Webhooks
 	torrentClientDebug         bool
 }
 
@@ -156,6 +161,7 @@ const (
 	PieceHashersPerTorrentFlag     = "piece-hashers-per-torrent"
 	DialRateLimitFlag              = "dial-rate-limit"
 	PerTorrentCacheBudgetFlag      = "per-torrent-cache-budget"
+	CacheBudgetFlag                = "cache-budget"
 )
 
 func RegisterTorrentClientFlags(f []cli.Flag) []cli.Flag {
@@ -286,10 +292,16 @@ func RegisterTorrentClientFlags(f []cli.Flag) []cli.Flag {
 			Value:  "50GB",
 			EnvVar: "PER_TORRENT_CACHE_BUDGET",
 		},
+		cli.StringFlag{
+			Name:   CacheBudgetFlag,
+			Usage:  "global cache budget shared by all torrents (e.g. 500GB, 0 = unlimited)",
+			Value:  "0",
+			EnvVar: "CACHE_BUDGET",
+		},
 	)
 }
 
-func NewTorrentClient(c *cli.Context) (*TorrentClient, error) {
+func NewTorrentClient(c *cli.Context, wh *Webhooks) (*TorrentClient, error) {
 	dr := int64(-1)
 	if c.String(TorrentClientDownloadRateFlag) != "" {
 		drp, err := bytefmt.ToBytes(c.String(TorrentClientDownloadRateFlag))
@@ -315,9 +327,22 @@ func NewTorrentClient(c *cli.Context) (*TorrentClient, error) {
 		}
 		cacheBudget = int64(cb)
 	}
+	var globalCacheBudget int64
+	if c.String(CacheBudgetFlag) != "" && c.String(CacheBudgetFlag) != "0" {
+		cb, err := bytefmt.ToBytes(c.String(CacheBudgetFlag))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse cache budget flag")
+		}
+		globalCacheBudget = int64(cb)
+	}
+	disk, err := NewDiskMonitor(c)
+	if err != nil {
+		return nil, err
+	}
 	return &TorrentClient{
 		rLimit:                     dr,
 		dataDir:                    c.String(DataDirFlag),
+		storage:                    c.String(StorageFlag),
 		proxy:                      c.String(HttpProxyFlag),
 		ua:                         c.String(TorrentClientUserAgentFlag),
 		dUTP:                       c.Bool(DisableUtpFlag),
@@ -339,12 +364,15 @@ func NewTorrentClient(c *cli.Context) (*TorrentClient, error) {
 		pieceHashersPerTorrent:     c.Int(PieceHashersPerTorrentFlag),
 		dialRateLimit:              c.Int(DialRateLimitFlag),
 		perTorrentCacheBudget:      cacheBudget,
+		cacheBudget:                globalCacheBudget,
+		disk:                       disk,
+		wh:                         wh,
 		torrentClientDebug:         c.Bool(TorrentClientDebugFlag),
 	}, nil
 }
 
 func (s *TorrentClient) get() (*torrent.Client, error) {
-	log.Infof("initializing TorrentClient dataDir=%v", s.dataDir)
+	log.Infof("initializing TorrentClient dataDir=%v storage=%v", s.dataDir, s.storage)
 	cfg := torrent.NewDefaultClientConfig()
 	// cfg.DisableIPv6 = true
 	if s.torrentClientDebug {
@@ -355,7 +383,18 @@ func (s *TorrentClient) get() (*torrent.Client, error) {
 		l.SetHandlers(tlog.DiscardHandler)
 		cfg.Logger = l
 	}
-	s.storageImpl = NewMMap(s.dataDir, s.perTorrentCacheBudget)
+	var global *CacheBudget
+	if s.cacheBudget > 0 {
+		global = NewCacheBudget(s.cacheBudget)
+	}
+	si, err := NewStorage(s.storage, s.dataDir, s.perTorrentCacheBudget, global, s.disk, s.wh)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to init storage")
+	}
+	s.storageImpl = si
+	if s.disk != nil {
+		go s.disk.Run()
+	}
 	cfg.DefaultStorage = s.storageImpl
 	if s.ua != "" {
 		cfg.HTTPUserAgent = s.ua
@@ -496,7 +535,30 @@ func (s *TorrentClient) Get() (*torrent.Client, error) {
 	return s.cl, s.err
 }
 
+// pieceCompletion returns piece completion of open torrent h, nil if torrent
+// is not open.
+func (s *TorrentClient) pieceCompletion(h string) *pieceCompletion {
+	s.mux.Lock()
+	si := s.storageImpl
+	s.mux.Unlock()
+	if si == nil {
+		return nil
+	}
+	return si.completion(metainfo.NewHashFromHex(h))
+}
+
+// Admit checks whether a new torrent h can be added to the client.
+func (s *TorrentClient) Admit(h string) error {
+	if s.disk == nil {
+		return nil
+	}
+	return s.disk.Admit(h)
+}
+
 func (s *TorrentClient) Close() {
+	if s.disk != nil {
+		s.disk.Close()
+	}
 	if s.cl != nil {
 		log.Infof("closing TorrentClient")
 		s.cl.Close()
@@ -10,12 +10,14 @@ import (
 type TorrentFileCountMap struct {
 	lazymap.LazyMap[*metainfo.Info]
 	fsm *FileStoreMap
+	msm *MagnetStoreMap
 	tsm *TorrentStoreMap
 }
 
-func NewTorrentFileCountMap(fsm *FileStoreMap, tsm *TorrentStoreMap) *TorrentFileCountMap {
+func NewTorrentFileCountMap(fsm *FileStoreMap, msm *MagnetStoreMap, tsm *TorrentStoreMap) *TorrentFileCountMap {
 	return &TorrentFileCountMap{
 		fsm: fsm,
+		msm: msm,
 		tsm: tsm,
 		LazyMap: lazymap.New[*metainfo.Info](&lazymap.Config{
 			Capacity: 100,
@@ -28,6 +30,12 @@ func (s *TorrentFileCountMap) getInfo(h string) (*metainfo.Info, error) {
 	if err != nil {
 		return nil, err
 	}
+	if mi == nil {
+		mi, err = s.msm.Get(h)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if mi == nil {
 		mi, err = s.tsm.Get(h)
 		if err != nil {
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/anacrolix/torrent/metainfo"
+	sqlite "github.com/go-llsqlite/adapter"
+	"github.com/go-llsqlite/adapter/sqlitex"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	TorrentSourceInput  = "input"
+	TorrentSourceActive = "active"
+	TorrentSourceDisk   = "disk"
+
+	CacheStateEmpty    = "empty"
+	CacheStatePartial  = "partial"
+	CacheStateComplete = "complete"
+)
+
+var infoHashR = regexp.MustCompile("^[0-9a-f]{40}$")
+
+// TorrentIndexItem is a torrent known to the node, either from input, active
+// in client or having data on disk.
+type TorrentIndexItem struct {
+	InfoHash    string
+	Sources     []string
+	CacheState  string
+	CachedBytes int64
+}
+
+// Index returns page of known torrents sorted by info-hash together with
+// total number of torrents. limit <= 0 returns all torrents from offset.
+// Cache state is resolved only for returned torrents.
+func (s *TorrentMap) Index(offset int, limit int) ([]TorrentIndexItem, int, error) {
+	sources := map[string][]string{}
+	l, err := s.fsm.List()
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, h := range l {
+		sources[h] = append(sources[h], TorrentSourceInput)
+	}
+	s.mux.Lock()
+	for h := range s.entries {
+		sources[h] = append(sources[h], TorrentSourceActive)
+	}
+	s.mux.Unlock()
+	for _, h := range s.diskHashes() {
+		sources[h] = append(sources[h], TorrentSourceDisk)
+	}
+	hs := make([]string, 0, len(sources))
+	for h := range sources {
+		hs = append(hs, h)
+	}
+	sort.Strings(hs)
+	total := len(hs)
+	offset = min(max(offset, 0), total)
+	end := total
+	if limit > 0 {
+		end = min(offset+limit, total)
+	}
+	items := make([]TorrentIndexItem, 0, end-offset)
+	for _, h := range hs[offset:end] {
+		i := TorrentIndexItem{InfoHash: h, Sources: sources[h]}
+		i.CacheState, i.CachedBytes = s.cacheState(h)
+		items = append(items, i)
+	}
+	return items, total, nil
+}
+
+// diskHashes lists torrents having data dirs on any data dir shard.
+func (s *TorrentMap) diskHashes() []string {
+	shards, err := dataDirShards(s.dataDir)
+	if err != nil {
+		log.WithError(err).Warnf("failed to list data dir shards location=%v", s.dataDir)
+		return nil
+	}
+	var hs []string
+	for _, shard := range shards {
+		entries, err := os.ReadDir(shard)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() && infoHashR.MatchString(e.Name()) {
+				hs = append(hs, e.Name())
+			}
+		}
+	}
+	return hs
+}
+
+// cacheState reports how much of torrent h is cached. Active torrents are
+// checked in client, others by piece completion stored in data dir.
+func (s *TorrentMap) cacheState(h string) (string, int64) {
+	s.mux.Lock()
+	e, ok := s.entries[h]
+	s.mux.Unlock()
+	if ok && e.t.Info() != nil {
+		return cacheStateOf(e.t.BytesCompleted(), e.t.Length()), e.t.BytesCompleted()
+	}
+	dir, err := GetDir(s.dataDir, h)
+	if err != nil {
+		return CacheStateEmpty, 0
+	}
+	complete, err := completePiecesCount(dir)
+	if err != nil || complete == 0 {
+		return CacheStateEmpty, 0
+	}
+	info := s.localInfo(h)
+	if info == nil {
+		return CacheStatePartial, 0
+	}
+	cached := min(int64(complete)*info.PieceLength, info.TotalLength())
+	if complete >= info.NumPieces() {
+		cached = info.TotalLength()
+	}
+	return cacheStateOf(cached, info.TotalLength()), cached
+}
+
+func cacheStateOf(cached int64, length int64) string {
+	if cached <= 0 {
+		return CacheStateEmpty
+	}
+	if cached >= length {
+		return CacheStateComplete
+	}
+	return CacheStatePartial
+}
+
+// localInfo returns torrent info available without remote torrent store.
+func (s *TorrentMap) localInfo(h string) *metainfo.Info {
+	mi, err := s.fsm.Get(h)
+	if err != nil || mi == nil {
+		mi, err = s.msm.Get(h)
+	}
+	if err != nil || mi == nil {
+		return nil
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return nil
+	}
+	return &info
+}
+
+// completePiecesCount reads number of complete pieces from torrent data dir.
+func completePiecesCount(dir string) (int, error) {
+	f := filepath.Join(dir, ".torrent.db")
+	if _, err := os.Stat(f); err != nil {
+		return 0, err
+	}
+	db, err := sqlite.OpenConn(f, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer func(db *sqlite.Conn) {
+		_ = db.Close()
+	}(db)
+	var count int
+	err = sqlitex.Exec(db, `select count(*) from piece_completion where complete`,
+		func(stmt *sqlite.Stmt) error {
+			count = stmt.ColumnInt(0)
+			return nil
+		})
+	return count, err
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTorrentMap_Index(t *testing.T) {
+	input := t.TempDir()
+	b, err := os.ReadFile("../../torrents/Sintel.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(input, "sintel.torrent"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fsm := &FileStoreMap{p: input, files: map[string]*inputFile{}}
+	hs, err := fsm.List()
+	if err != nil || len(hs) != 1 {
+		t.Fatalf("failed to load input %v %v", hs, err)
+	}
+	sintel := hs[0]
+
+	dataDir := t.TempDir()
+	disk := strings.Repeat("f", 40)
+	for _, d := range []string{disk, sintel, "not-a-hash"} {
+		if err := os.Mkdir(filepath.Join(dataDir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tm := &TorrentMap{fsm: fsm, dataDir: dataDir, entries: map[string]*torrentEntry{}}
+
+	items, total, err := tm.Index(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(items) != 2 {
+		t.Fatalf("expected 2 torrents, got total=%v items=%v", total, items)
+	}
+	if items[0].InfoHash != sintel || len(items[0].Sources) != 2 ||
+		items[0].Sources[0] != TorrentSourceInput || items[0].Sources[1] != TorrentSourceDisk {
+		t.Fatalf("unexpected input item %+v", items[0])
+	}
+	if items[1].InfoHash != disk || len(items[1].Sources) != 1 || items[1].Sources[0] != TorrentSourceDisk {
+		t.Fatalf("unexpected disk item %+v", items[1])
+	}
+	for _, i := range items {
+		if i.CacheState != CacheStateEmpty {
+			t.Fatalf("expected empty cache state, got %+v", i)
+		}
+	}
+
+	items, total, err = tm.Index(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(items) != 1 || items[0].InfoHash != disk {
+		t.Fatalf("unexpected second page total=%v items=%v", total, items)
+	}
+	items, _, err = tm.Index(5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("expected empty page beyond total, got %v", items)
+	}
+}
+
+func TestCacheStateOf(t *testing.T) {
+	for _, c := range []struct {
+		cached, length int64
+		state          string
+	}{
+		{0, 100, CacheStateEmpty},
+		{50, 100, CacheStatePartial},
+		{100, 100, CacheStateComplete},
+	} {
+		if s := cacheStateOf(c.cached, c.length); s != c.state {
+			t.Fatalf("expected %v for %v/%v, got %v", c.state, c.cached, c.length, s)
+		}
+	}
+}
@@ -3,28 +3,66 @@ package services
 import (
 	"context"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/urfave/cli"
 
 	"github.com/anacrolix/torrent"
 	log "github.com/sirupsen/logrus"
 )
 
-//const (
-//	MagnetFlag = "magnet"
-//)
+const (
+	MagnetTimeoutFlag = "magnet-timeout"
+	VaultWebseedFlag  = "vault-webseed"
+	TorrentTTLFlag    = "torrent-ttl"
+	MaxTorrentTTLFlag = "max-torrent-ttl"
+	MaxActiveFlag     = "max-active-torrents"
+)
 
-//func RegisterTorrentMapFlags(f []cli.Flag) []cli.Flag {
-//	return append(f,
-//		cli.StringFlag{
-//			Name:   MagnetFlag,
-//			Usage:  "magnet",
-//			EnvVar: "MagnetFlag",
-//		},
-//	)
-//}
+const (
+	dropReasonIdle     = "idle"
+	dropReasonAdmin    = "admin"
+	dropReasonCapacity = "capacity"
+)
+
+func RegisterTorrentMapFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.DurationFlag{
+			Name:   MagnetTimeoutFlag,
+			Usage:  "max time to wait for magnet metadata",
+			Value:  60 * time.Second,
+			EnvVar: "MAGNET_TIMEOUT",
+		},
+		cli.BoolFlag{
+			Name:   VaultWebseedFlag,
+			Usage:  "add vault as web seed to torrents",
+			EnvVar: "VAULT_WEBSEED",
+		},
+		cli.DurationFlag{
+			Name:   TorrentTTLFlag,
+			Usage:  "time an idle torrent is kept active",
+			Value:  600 * time.Second,
+			EnvVar: "TORRENT_TTL",
+		},
+		cli.DurationFlag{
+			Name:   MaxTorrentTTLFlag,
+			Usage:  "max keep-alive window a client may request for a torrent",
+			Value:  time.Hour,
+			EnvVar: "MAX_TORRENT_TTL",
+		},
+		cli.IntFlag{
+			Name:   MaxActiveFlag,
+			Usage:  "max number of concurrently active torrents, 0 means unlimited",
+			Value:  0,
+			EnvVar: "MAX_ACTIVE_TORRENTS",
+		},
+	)
+}
 
 var (
 	promActiveTorrentCount = prometheus.NewGauge(prometheus.GaugeOpts{
@@ -63,6 +101,14 @@ var (
 		Name: "torrent_web_seeder_stall_download_seconds_total",
 		Help: "Total number of seconds when there was no data transferred and data was already received",
 	})
+	promDroppedTorrents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "torrent_web_seeder_dropped_torrents_total",
+		Help: "Total number of dropped torrents by reason",
+	}, []string{"reason"})
+	promWebseedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "torrent_web_seeder_webseed_bytes_total",
+		Help: "Total number of useful bytes downloaded from web seeds",
+	})
 )
 
 func init() {
@@ -74,39 +120,190 @@ func init() {
 	prometheus.MustRegister(promStallDiscoverySeconds)
 	prometheus.MustRegister(promStallIdleSeconds)
 	prometheus.MustRegister(promStallDownloadSeconds)
+	prometheus.MustRegister(promWebseedBytes)
+	prometheus.MustRegister(promDroppedTorrents)
+}
+
+var (
+	ErrMagnetTimeout   = errors.New("magnet metadata timeout")
+	ErrTooManyTorrents = errors.New("too many active torrents")
+)
+
+// torrentEntry is an active torrent tracked by TorrentMap.
+type torrentEntry struct {
+	t       *torrent.Torrent
+	timer   *time.Timer
+	added   time.Time
+	touched time.Time
+	ttl     time.Duration
+	pinned  bool
+	readers int
+	// keepUntil keeps torrent active regardless of ttl, e.g. for prefetch.
+	keepUntil time.Time
+}
+
+// idleTimeout returns time after which idle torrent is dropped.
+func (e *torrentEntry) idleTimeout() time.Duration {
+	return max(e.ttl, time.Until(e.keepUntil))
+}
+
+// ActiveTorrent is a snapshot of an active torrent for the admin API.
+type ActiveTorrent struct {
+	InfoHash    string    `json:"info_hash"`
+	Name        string    `json:"name"`
+	Added       time.Time `json:"added"`
+	LastTouch   time.Time `json:"last_touch"`
+	Age         float64   `json:"age_seconds"`
+	TTL         float64   `json:"ttl_seconds"`
+	BytesCached int64     `json:"bytes_cached"`
+	Peers       int       `json:"peers"`
+	Pinned      bool      `json:"pinned"`
+	Readers     int       `json:"readers"`
 }
 
 type TorrentMap struct {
-	tc     *TorrentClient
-	tsm    *TorrentStoreMap
-	fsm    *FileStoreMap
-	timers map[string]*time.Timer
-	ttl    time.Duration
-	mux    sync.Mutex
+	tc            *TorrentClient
+	tsm           *TorrentStoreMap
+	fsm           *FileStoreMap
+	msm           *MagnetStoreMap
+	v             *Vault
+	wh            *Webhooks
+	dataDir       string
+	vaultWebseed  bool
+	entries       map[string]*torrentEntry
+	pending       map[string]int // magnets waiting for metadata, hold a slot
+	priorities    map[string]map[string]FilePriority
+	saveMux       sync.Mutex // serializes writes of file priorities
+	ttl           time.Duration
+	maxTTL        time.Duration
+	maxActive     int
+	magnetTimeout time.Duration
+	mux           sync.Mutex
 }
 
-func NewTorrentMap(tc *TorrentClient, tsm *TorrentStoreMap, fsm *FileStoreMap) *TorrentMap {
-	return &TorrentMap{
-		tc:     tc,
-		tsm:    tsm,
-		fsm:    fsm,
-		timers: map[string]*time.Timer{},
-		ttl:    time.Duration(600) * time.Second,
+func NewTorrentMap(c *cli.Context, tc *TorrentClient, tsm *TorrentStoreMap, fsm *FileStoreMap, msm *MagnetStoreMap, v *Vault, wh *Webhooks) *TorrentMap {
+	tm := &TorrentMap{
+		tc:            tc,
+		tsm:           tsm,
+		fsm:           fsm,
+		msm:           msm,
+		v:             v,
+		wh:            wh,
+		dataDir:       c.String(DataDirFlag),
+		vaultWebseed:  v != nil && c.Bool(VaultWebseedFlag),
+		entries:       map[string]*torrentEntry{},
+		pending:       map[string]int{},
+		ttl:           c.Duration(TorrentTTLFlag),
+		maxTTL:        c.Duration(MaxTorrentTTLFlag),
+		maxActive:     c.Int(MaxActiveFlag),
+		magnetTimeout: c.Duration(MagnetTimeoutFlag),
+	}
+	err := tm.loadFilePriorities()
+	if err != nil {
+		log.WithError(err).Warn("failed to load file priorities")
 	}
+	return tm
 }
 
 func (s *TorrentMap) Touch(h string) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
-	ti, ok := s.timers[h]
+	e, ok := s.entries[h]
 	if ok {
-		ti.Reset(s.ttl)
+		s.touch(e)
+	}
+}
+
+// touch records activity and resets TTL timer of unpinned torrent.
+// Must be called with s.mux held.
+func (s *TorrentMap) touch(e *torrentEntry) {
+	e.touched = time.Now()
+	if !e.pinned {
+		e.timer.Reset(e.idleTimeout())
 	}
 }
 
+// KeepAlive keeps active torrent h from being dropped for at least d.
+// It reports whether torrent was active.
+func (s *TorrentMap) KeepAlive(h string, d time.Duration) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	e, ok := s.entries[h]
+	if !ok {
+		return false
+	}
+	if until := time.Now().Add(d); until.After(e.keepUntil) {
+		e.keepUntil = until
+	}
+	s.touch(e)
+	return true
+}
+
+type torrentTTLKey struct{}
+
+// WithTorrentTTL asks TorrentMap to keep torrents requested with ctx active
+// for at least ttl after the last activity. It is capped by max torrent TTL.
+func WithTorrentTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, torrentTTLKey{}, ttl)
+}
+
+// ParseTorrentTTL parses TTL given either as duration ("2h") or as seconds.
+func ParseTorrentTTL(v string) (time.Duration, error) {
+	if sec, err := strconv.Atoi(v); err == nil {
+		return time.Duration(sec) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid torrent ttl %q", v)
+	}
+	return d, nil
+}
+
+// requestedTTL returns TTL of torrent requested with ctx.
+func (s *TorrentMap) requestedTTL(ctx context.Context) time.Duration {
+	ttl, ok := ctx.Value(torrentTTLKey{}).(time.Duration)
+	if !ok || ttl <= s.ttl {
+		return s.ttl
+	}
+	if ttl > s.maxTTL {
+		ttl = max(s.maxTTL, s.ttl)
+	}
+	return ttl
+}
+
 func (s *TorrentMap) Get(ctx context.Context, h string) (*torrent.Torrent, error) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
+	return s.get(ctx, h)
+}
+
+// Open gets torrent for reading its content. Torrent with open readers is
+// never dropped to make room for another one. Returned release func must be
+// called once reading is done.
+func (s *TorrentMap) Open(ctx context.Context, h string) (*torrent.Torrent, func(), error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	t, err := s.get(ctx, h)
+	if err != nil || t == nil {
+		return t, func() {}, err
+	}
+	e := s.entries[h]
+	e.readers++
+	var once sync.Once
+	return t, func() {
+		once.Do(func() {
+			s.mux.Lock()
+			defer s.mux.Unlock()
+			e.readers--
+			if s.entries[h] == e {
+				s.touch(e)
+			}
+		})
+	}, nil
+}
+
+// get must be called with s.mux held.
+func (s *TorrentMap) get(ctx context.Context, h string) (*torrent.Torrent, error) {
 	cl, err := s.tc.Get()
 	if err != nil {
 		return nil, err
@@ -116,6 +313,12 @@ func (s *TorrentMap) Get(ctx context.Context, h string) (*torrent.Torrent, error
 	if err != nil {
 		return nil, err
 	}
+	if mi == nil {
+		mi, err = s.msm.Get(h)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if mi == nil {
 		mi, err = s.tsm.Get(h)
 		if err != nil {
@@ -126,16 +329,108 @@ func (s *TorrentMap) Get(ctx context.Context, h string) (*torrent.Torrent, error
 		return nil, nil
 
 	}
+	err = s.reserve(h)
+	if err != nil {
+		return nil, err
+	}
 	t, err = cl.AddTorrent(mi)
 	if err != nil {
 		return nil, err
 	}
-	ti, ok := s.timers[h]
+	s.track(h, t, s.requestedTTL(ctx))
+	return t, nil
+}
+
+// AddMagnet adds a magnet link to the client and waits for its metadata.
+// Resolved metainfo is cached on disk, so subsequent Get calls treat the
+// torrent exactly like a stored one.
+func (s *TorrentMap) AddMagnet(ctx context.Context, uri string) (*torrent.Torrent, error) {
+	m, err := metainfo.ParseMagnetUri(uri)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse magnet")
+	}
+	h := m.InfoHash.HexString()
+	t, err := s.Get(ctx, h)
+	if err != nil {
+		log.WithError(err).Debugf("no stored torrent for magnet infohash=%v", h)
+	} else if t != nil {
+		return t, nil
+	}
+	cl, err := s.tc.Get()
+	if err != nil {
+		return nil, err
+	}
+	s.mux.Lock()
+	err = s.reserve(h)
+	if err == nil {
+		s.pending[h]++
+	}
+	s.mux.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		s.pending[h]--
+		if s.pending[h] <= 0 {
+			delete(s.pending, h)
+		}
+	}()
+	t, err = cl.AddMagnet(uri)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to add magnet")
+	}
+	log.Infof("waiting for magnet metadata infohash=%v", h)
+	ctx, cancel := context.WithTimeout(ctx, s.magnetTimeout)
+	defer cancel()
+	select {
+	case <-t.GotInfo():
+	case <-ctx.Done():
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		if _, ok := s.entries[h]; !ok && s.pending[h] <= 1 {
+			t.Drop()
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, errors.Wrapf(ErrMagnetTimeout, "infohash=%v", h)
+		}
+		return nil, ctx.Err()
+	}
+	mi := t.Metainfo()
+	err = s.msm.Set(h, &mi)
+	if err != nil {
+		log.WithError(err).Warnf("failed to cache magnet metadata infohash=%v", h)
+	}
+	go func() {
+		err := s.tsm.Push(h, &mi)
+		if err != nil {
+			log.WithError(err).Warnf("failed to push magnet metadata infohash=%v", h)
+		}
+	}()
+	ttl := s.requestedTTL(ctx)
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.track(h, t, ttl)
+	return t, nil
+}
+
+// track starts or resets the TTL timer of the torrent.
+// Must be called with s.mux held.
+func (s *TorrentMap) track(h string, t *torrent.Torrent, ttl time.Duration) {
+	e, ok := s.entries[h]
 	if ok {
-		ti.Reset(s.ttl)
+		e.ttl = max(e.ttl, ttl)
+		s.touch(e)
 	} else {
 		log.Infof("torrent added infohash=%v", h)
 		promActiveTorrentCount.Inc()
+		if s.vaultWebseed {
+			go s.addVaultWebseed(h, t)
+		}
+		if len(s.priorities[h]) > 0 {
+			go s.applyFilePriorities(h, t)
+		}
 		startTime := time.Now()
 		go func() {
 			const tickDuration = time.Millisecond * 50
@@ -146,6 +441,7 @@ func (s *TorrentMap) Get(ctx context.Context, h string) (*torrent.Torrent, error
 			thirtyPeersRecorded := false
 			firstByteRecorded := false
 			var lastBytesRead int64
+			var lastWebseedBytes int64
 			for {
 				select {
 				case <-t.Closed():
@@ -164,6 +460,9 @@ func (s *TorrentMap) Get(ctx context.Context, h string) (*torrent.Torrent, error
 						}
 					}
 					lastBytesRead = bytesRead
+					webseedBytes := webseedBytesRead(t)
+					promWebseedBytes.Add(float64(webseedBytes - lastWebseedBytes))
+					lastWebseedBytes = webseedBytes
 					if !firstPeerRecorded && activePeers > 0 {
 						promTimeToFirstPeerMs.Observe(float64(time.Since(startTime).Milliseconds()))
 						firstPeerRecorded = true
@@ -183,34 +482,165 @@ func (s *TorrentMap) Get(ctx context.Context, h string) (*torrent.Torrent, error
 				}
 			}
 		}()
-		ti := time.NewTimer(s.ttl)
-		s.timers[h] = ti
-		go func(h string, ti *time.Timer) {
-			<-ti.C
+		now := time.Now()
+		e := &torrentEntry{
+			t:       t,
+			added:   now,
+			touched: now,
+			ttl:     ttl,
+		}
+		e.timer = time.AfterFunc(ttl, func() {
 			s.mux.Lock()
 			defer s.mux.Unlock()
-			delete(s.timers, h)
-			log.Infof("torrent dropped infohash=%v", h)
-			t.Drop()
-			promActiveTorrentCount.Dec()
-		}(h, ti)
+			if s.entries[h] == e && !e.pinned {
+				s.drop(h, e, dropReasonIdle)
+			}
+		})
+		s.entries[h] = e
 	}
-	return t, nil
 }
 
-func (s *TorrentMap) List() ([]string, error) {
-	r := map[string]bool{}
-	l, err := s.fsm.List()
+// reserve makes room for torrent h by dropping the least recently touched
+// unpinned torrent without open readers and keep-alive window, if active
+// torrents limit is reached. Magnets waiting for metadata count against the
+// limit.
+// Must be called with s.mux held.
+func (s *TorrentMap) reserve(h string) error {
+	if _, ok := s.entries[h]; ok || s.pending[h] > 0 {
+		return nil
+	}
+	err := s.tc.Admit(h)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if s.maxActive <= 0 || len(s.entries)+len(s.pending) < s.maxActive {
+		return nil
+	}
+	var lh string
+	var lru *torrentEntry
+	now := time.Now()
+	for eh, e := range s.entries {
+		if e.pinned || e.readers > 0 || now.Before(e.keepUntil) {
+			continue
+		}
+		if lru == nil || e.touched.Before(lru.touched) {
+			lh, lru = eh, e
+		}
 	}
-	for _, t := range l {
-		r[t] = true
+	if lru == nil {
+		return errors.Wrapf(ErrTooManyTorrents, "limit=%v", s.maxActive)
+	}
+	s.drop(lh, lru, dropReasonCapacity)
+	return nil
+}
+
+// IsActive reports whether torrent h is open.
+func (s *TorrentMap) IsActive(h string) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	_, ok := s.entries[h]
+	return ok
+}
+
+// IfInactive runs fn only if torrent h is not open. Torrent can not be opened
+// while fn runs, so fn must be fast as it blocks all torrent requests.
+func (s *TorrentMap) IfInactive(h string, fn func() error) (bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.entries[h]; ok {
+		return false, nil
+	}
+	return true, fn()
+}
+
+// drop stops tracking the torrent and drops it from the client.
+// Must be called with s.mux held.
+func (s *TorrentMap) drop(h string, e *torrentEntry, reason string) {
+	e.timer.Stop()
+	delete(s.entries, h)
+	log.Infof("torrent dropped infohash=%v reason=%v", h, reason)
+	e.t.Drop()
+	promActiveTorrentCount.Dec()
+	promDroppedTorrents.WithLabelValues(reason).Inc()
+	s.wh.Emit(WebhookEvent{Type: EventTorrentDropped, InfoHash: h, Reason: reason})
+}
+
+// Drop forcibly drops active torrent. It reports whether torrent was active.
+func (s *TorrentMap) Drop(h string) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	e, ok := s.entries[h]
+	if !ok {
+		return false
+	}
+	s.drop(h, e, dropReasonAdmin)
+	return true
+}
+
+// Pin keeps active torrent from being dropped by TTL until it is unpinned.
+// It reports whether torrent was active.
+func (s *TorrentMap) Pin(h string, pinned bool) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	e, ok := s.entries[h]
+	if !ok {
+		return false
+	}
+	e.pinned = pinned
+	if pinned {
+		e.timer.Stop()
+	} else {
+		e.timer.Reset(e.idleTimeout())
+	}
+	log.Infof("torrent pinned=%v infohash=%v", pinned, h)
+	return true
+}
+
+// Active returns snapshots of active torrents sorted by info-hash.
+func (s *TorrentMap) Active() []ActiveTorrent {
+	s.mux.Lock()
+	entries := make(map[string]torrentEntry, len(s.entries))
+	for h, e := range s.entries {
+		entries[h] = *e
+	}
+	s.mux.Unlock()
+	res := make([]ActiveTorrent, 0, len(entries))
+	for h, e := range entries {
+		at := ActiveTorrent{
+			InfoHash:  h,
+			Added:     e.added,
+			LastTouch: e.touched,
+			Age:       time.Since(e.added).Seconds(),
+			TTL:       e.ttl.Seconds(),
+			Peers:     e.t.Stats().ActivePeers,
+			Pinned:    e.pinned,
+			Readers:   e.readers,
+		}
+		if e.t.Info() != nil {
+			at.Name = e.t.Name()
+			at.BytesCached = e.t.BytesCompleted()
+		}
+		res = append(res, at)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].InfoHash < res[j].InfoHash
+	})
+	return res
+}
+
+// addVaultWebseed adds vault to the torrent as a web seed, so pieces missing
+// locally are fetched over HTTP in parallel with peers.
+func (s *TorrentMap) addVaultWebseed(h string, t *torrent.Torrent) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	u, err := s.v.GetWebseedURL(ctx, h)
+	if err != nil {
+		log.WithError(err).Warnf("failed to get vault webseed infohash=%v", h)
+		return
 	}
-	rr := []string{}
-	for k := range r {
-		rr = append(rr, k)
+	if u == "" {
+		return
 	}
-	sort.Strings(rr)
-	return rr, nil
+	log.Infof("adding vault webseed infohash=%v url=%v", h, u)
+	t.AddWebSeeds([]string{u})
 }
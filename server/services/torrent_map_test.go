@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newTestClient returns torrent client without any network activity.
+func newTestClient(t *testing.T) *torrent.Client {
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = t.TempDir()
+	cfg.NoDHT = true
+	cfg.DisableTCP = true
+	cfg.DisableUTP = true
+	cfg.DisableTrackers = true
+	cfg.NoDefaultPortForwarding = true
+	cfg.ListenPort = 0
+	cl, err := torrent.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cl.Close() })
+	return cl
+}
+
+// addTestEntry tracks new torrent with hash of single repeated char c.
+func addTestEntry(t *testing.T, tm *TorrentMap, cl *torrent.Client, c string, e torrentEntry) string {
+	h := strings.Repeat(c, 40)
+	tr, _ := cl.AddTorrentInfoHash(metainfo.NewHashFromHex(h))
+	e.t = tr
+	e.timer = time.NewTimer(time.Hour)
+	tm.entries[h] = &e
+	return h
+}
+
+func TestTorrentMap_Reserve(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		name      string
+		maxActive int
+		pending   int
+		entries   map[string]torrentEntry
+		dropped   string
+		err       error
+	}{
+		{
+			name:      "unlimited",
+			maxActive: 0,
+			entries:   map[string]torrentEntry{"a": {touched: now.Add(-time.Hour)}},
+		},
+		{
+			name:      "below limit",
+			maxActive: 2,
+			entries:   map[string]torrentEntry{"a": {touched: now.Add(-time.Hour)}},
+		},
+		{
+			name:      "least recently touched dropped",
+			maxActive: 2,
+			entries: map[string]torrentEntry{
+				"a": {touched: now.Add(-time.Minute)},
+				"b": {touched: now.Add(-time.Hour)},
+			},
+			dropped: "b",
+		},
+		{
+			name:      "pinned and read torrents kept",
+			maxActive: 3,
+			entries: map[string]torrentEntry{
+				"a": {touched: now.Add(-time.Hour), pinned: true},
+				"b": {touched: now.Add(-time.Hour), readers: 1},
+				"c": {touched: now},
+			},
+			dropped: "c",
+		},
+		{
+			name:      "kept alive torrents kept",
+			maxActive: 2,
+			entries: map[string]torrentEntry{
+				"a": {touched: now.Add(-time.Hour), keepUntil: now.Add(time.Minute)},
+				"b": {touched: now, keepUntil: now.Add(-time.Minute)},
+			},
+			dropped: "b",
+		},
+		{
+			name:      "pending magnet holds slot",
+			maxActive: 2,
+			pending:   1,
+			entries:   map[string]torrentEntry{"a": {touched: now}},
+			dropped:   "a",
+		},
+		{
+			name:      "nothing to drop",
+			maxActive: 2,
+			entries: map[string]torrentEntry{
+				"a": {touched: now, pinned: true},
+				"b": {touched: now, readers: 2},
+			},
+			err: ErrTooManyTorrents,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cl := newTestClient(t)
+			tm := &TorrentMap{
+				tc:        &TorrentClient{},
+				entries:   map[string]*torrentEntry{},
+				pending:   map[string]int{},
+				maxActive: tc.maxActive,
+			}
+			hashes := map[string]string{}
+			for c, e := range tc.entries {
+				hashes[c] = addTestEntry(t, tm, cl, c, e)
+			}
+			if tc.pending > 0 {
+				tm.pending[strings.Repeat("9", 40)] = tc.pending
+			}
+			err := tm.reserve(strings.Repeat("0", 40))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			for c, h := range hashes {
+				_, active := tm.entries[h]
+				if active == (c == tc.dropped) {
+					t.Fatalf("unexpected state of %v active=%v, expected dropped %q", c, active, tc.dropped)
+				}
+			}
+		})
+	}
+}
+
+func TestTorrentMap_ReserveActiveOrPending(t *testing.T) {
+	cl := newTestClient(t)
+	tm := &TorrentMap{
+		tc:        &TorrentClient{},
+		entries:   map[string]*torrentEntry{},
+		pending:   map[string]int{strings.Repeat("b", 40): 1},
+		maxActive: 1,
+	}
+	h := addTestEntry(t, tm, cl, "a", torrentEntry{pinned: true})
+	for _, rh := range []string{h, strings.Repeat("b", 40)} {
+		if err := tm.reserve(rh); err != nil {
+			t.Fatalf("expected already reserved torrent %v admitted, got %v", rh, err)
+		}
+	}
+}
+
+func TestTooManyTorrentsMapping(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code int
+		grpc codes.Code
+	}{
+		{errors.Wrapf(ErrTooManyTorrents, "limit=%v", 1), http.StatusTooManyRequests, codes.ResourceExhausted},
+		{errors.Wrap(ErrLowDiskSpace, "free=0"), http.StatusServiceUnavailable, codes.ResourceExhausted},
+		{errors.New("boom"), http.StatusInternalServerError, codes.Unknown},
+	} {
+		rec := httptest.NewRecorder()
+		(&WebSeeder{}).renderTorrentError(rec, tc.err)
+		if rec.Code != tc.code {
+			t.Fatalf("expected http status %v for %v, got %v", tc.code, tc.err, rec.Code)
+		}
+		if tc.code != http.StatusInternalServerError && rec.Header().Get("Retry-After") == "" {
+			t.Fatalf("expected Retry-After for %v", tc.err)
+		}
+		if c := status.Code(torrentStatusError(tc.err)); c != tc.grpc {
+			t.Fatalf("expected grpc code %v for %v, got %v", tc.grpc, tc.err, c)
+		}
+	}
+}
+
+func TestParseTorrentTTL(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		ttl time.Duration
+		err bool
+	}{
+		{in: "7200", ttl: 2 * time.Hour},
+		{in: "0", ttl: 0},
+		{in: "2h", ttl: 2 * time.Hour},
+		{in: "90m30s", ttl: 90*time.Minute + 30*time.Second},
+		{in: "1.5h", ttl: 90 * time.Minute},
+		{in: "10", ttl: 10 * time.Second},
+		{in: "2 hours", err: true},
+		{in: "h", err: true},
+	} {
+		ttl, err := ParseTorrentTTL(tc.in)
+		if (err != nil) != tc.err {
+			t.Fatalf("unexpected error for %q: %v", tc.in, err)
+		}
+		if ttl != tc.ttl {
+			t.Fatalf("expected ttl %v for %q, got %v", tc.ttl, tc.in, ttl)
+		}
+	}
+}
+
+func TestTorrentMap_RequestedTTL(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		ttl       time.Duration
+		maxTTL    time.Duration
+		requested time.Duration
+		set       bool
+		expected  time.Duration
+	}{
+		{name: "not requested", ttl: time.Minute, maxTTL: time.Hour, expected: time.Minute},
+		{name: "below default", ttl: time.Minute, maxTTL: time.Hour, requested: time.Second, set: true, expected: time.Minute},
+		{name: "zero", ttl: time.Minute, maxTTL: time.Hour, requested: 0, set: true, expected: time.Minute},
+		{name: "above default", ttl: time.Minute, maxTTL: time.Hour, requested: 10 * time.Minute, set: true, expected: 10 * time.Minute},
+		{name: "equal to max", ttl: time.Minute, maxTTL: time.Hour, requested: time.Hour, set: true, expected: time.Hour},
+		{name: "capped at max", ttl: time.Minute, maxTTL: time.Hour, requested: 5 * time.Hour, set: true, expected: time.Hour},
+		{name: "max below default", ttl: time.Hour, maxTTL: time.Minute, requested: 2 * time.Hour, set: true, expected: time.Hour},
+		{name: "max below default and requested below default", ttl: time.Hour, maxTTL: time.Minute, requested: 10 * time.Minute, set: true, expected: time.Hour},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tm := &TorrentMap{ttl: tc.ttl, maxTTL: tc.maxTTL}
+			ctx := context.Background()
+			if tc.set {
+				ctx = WithTorrentTTL(ctx, tc.requested)
+			}
+			if ttl := tm.requestedTTL(ctx); ttl != tc.expected {
+				t.Fatalf("expected ttl %v, got %v", tc.expected, ttl)
+			}
+		})
+	}
+}
+
+func TestTorrentTTLContext(t *testing.T) {
+	tm := &TorrentMap{ttl: time.Minute, maxTTL: time.Hour}
+	for _, tc := range []struct {
+		name     string
+		md       metadata.MD
+		expected time.Duration
+		code     codes.Code
+	}{
+		{name: "no metadata", expected: time.Minute},
+		{name: "empty ttl", md: metadata.Pairs("torrent-ttl", ""), expected: time.Minute},
+		{name: "seconds", md: metadata.Pairs("torrent-ttl", "600"), expected: 10 * time.Minute},
+		{name: "duration", md: metadata.Pairs("torrent-ttl", "30m"), expected: 30 * time.Minute},
+		{name: "capped", md: metadata.Pairs("torrent-ttl", "48h"), expected: time.Hour},
+		{name: "invalid", md: metadata.Pairs("torrent-ttl", "forever"), code: codes.InvalidArgument},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tc.md)
+			}
+			ctx, err := torrentTTLContext(ctx)
+			if c := status.Code(err); c != tc.code {
+				t.Fatalf("expected code %v, got %v", tc.code, err)
+			}
+			if err != nil {
+				return
+			}
+			if ttl := tm.requestedTTL(ctx); ttl != tc.expected {
+				t.Fatalf("expected ttl %v, got %v", tc.expected, ttl)
+			}
+		})
+	}
+}
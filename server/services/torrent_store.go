@@ -70,6 +70,11 @@ func (s *TorrentStore) get() (ts.TorrentStoreClient, error) {
 	return ts.NewTorrentStoreClient(s.conn), nil
 }
 
+// Enabled reports whether torrent store host is configured.
+func (s *TorrentStore) Enabled() bool {
+	return s.host != ""
+}
+
 func (s *TorrentStore) Get() (ts.TorrentStoreClient, error) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
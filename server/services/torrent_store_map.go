@@ -14,7 +14,8 @@ import (
 
 type TorrentStoreMap struct {
 	lazymap.LazyMap[*metainfo.MetaInfo]
-	ts *TorrentStore
+	pushed lazymap.LazyMap[bool]
+	ts     *TorrentStore
 }
 
 func NewTorrentStoreMap(ts *TorrentStore) *TorrentStoreMap {
@@ -23,6 +24,9 @@ func NewTorrentStoreMap(ts *TorrentStore) *TorrentStoreMap {
 		LazyMap: lazymap.New[*metainfo.MetaInfo](&lazymap.Config{
 			Capacity: 1000,
 		}),
+		pushed: lazymap.New[bool](&lazymap.Config{
+			Capacity: 1000,
+		}),
 	}
 }
 
@@ -51,3 +55,37 @@ func (s *TorrentStoreMap) Get(h string) (*metainfo.MetaInfo, error) {
 		return s.get(h)
 	})
 }
+
+func (s *TorrentStoreMap) push(h string, mi *metainfo.MetaInfo) (bool, error) {
+	c, err := s.ts.Get()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get torrent store client")
+	}
+	var b bytes.Buffer
+	err = mi.Write(&b)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to encode torrent")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, err = c.Push(ctx, &ts.PushRequest{Torrent: b.Bytes()})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to push torrent to the torrent store")
+	}
+	log.Infof("torrent pushed successfully infohash=%v", h)
+	return true, nil
+}
+
+// Push stores metainfo in the torrent store, so other replicas don't have to
+// resolve it from the swarm again. Successful pushes are remembered and
+// repeated calls for the same infohash do nothing. Failed pushes are not
+// remembered and will be retried on the next call.
+func (s *TorrentStoreMap) Push(h string, mi *metainfo.MetaInfo) error {
+	if !s.ts.Enabled() {
+		return nil
+	}
+	_, err := s.pushed.Get(h, func() (bool, error) {
+		return s.push(h, mi)
+	})
+	return err
+}
@@ -27,7 +27,7 @@ func (s *TouchMap) touch(h string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	f := dir + ".touch"
+	f := dir + touchSuffix
 	_, err = os.Stat(f)
 	if os.IsNotExist(err) {
 		file, err := os.Create(f)
@@ -11,6 +11,7 @@ type TouchWriter struct {
 	http.ResponseWriter
 	tm *TorrentMap
 	h  string
+	ra *AdaptiveReadahead
 }
 
 func NewTouchWriter(w http.ResponseWriter, tm *TorrentMap, h string) *TouchWriter {
@@ -21,13 +22,23 @@ func NewTouchWriter(w http.ResponseWriter, tm *TorrentMap, h string) *TouchWrite
 	}
 }
 
+// WithReadahead makes writer report written bytes to readahead estimator.
+func (w *TouchWriter) WithReadahead(ra *AdaptiveReadahead) *TouchWriter {
+	w.ra = ra
+	return w
+}
+
 func (w *TouchWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
 func (w *TouchWriter) Write(p []byte) (int, error) {
 	w.tm.Touch(w.h)
-	return w.ResponseWriter.Write(p)
+	n, err := w.ResponseWriter.Write(p)
+	if w.ra != nil {
+		w.ra.Observe(n)
+	}
+	return n, err
 }
 
 func (w *TouchWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	WarmRestartFlag            = "warm-restart"
+	WarmRestartConcurrencyFlag = "warm-restart-concurrency"
+	warmRestartStateFile       = ".active-torrents.json"
+	warmRestartSaveInterval    = 30 * time.Second
+)
+
+func RegisterWarmRestartFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.BoolFlag{
+			Name:   WarmRestartFlag,
+			Usage:  "persist active torrents in data dir and re-add them on startup",
+			EnvVar: "WARM_RESTART",
+		},
+		cli.IntFlag{
+			Name:   WarmRestartConcurrencyFlag,
+			Usage:  "number of torrents re-added concurrently on warm restart",
+			Value:  4,
+			EnvVar: "WARM_RESTART_CONCURRENCY",
+		},
+	)
+}
+
+// warmTorrent is an active torrent recorded in warm restart state.
+type warmTorrent struct {
+	InfoHash string  `json:"info_hash"`
+	Pinned   bool    `json:"pinned,omitempty"`
+	TTL      float64 `json:"ttl_seconds,omitempty"`
+}
+
+type warmRestartState struct {
+	Torrents []warmTorrent `json:"torrents"`
+}
+
+// WarmRestart records active torrents in data dir and re-adds them in
+// background on startup, so peers are already connected when users return.
+type WarmRestart struct {
+	tm          *TorrentMap
+	path        string
+	concurrency int
+	saved       []byte
+	restored    bool
+	mux         sync.Mutex
+	closeCh     chan struct{}
+}
+
+// NewWarmRestart returns nil if warm restart is disabled.
+func NewWarmRestart(c *cli.Context, tm *TorrentMap) *WarmRestart {
+	if !c.Bool(WarmRestartFlag) {
+		return nil
+	}
+	concurrency := c.Int(WarmRestartConcurrencyFlag)
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &WarmRestart{
+		tm:          tm,
+		path:        filepath.Join(dataDirRoot(c.String(DataDirFlag)), warmRestartStateFile),
+		concurrency: concurrency,
+		closeCh:     make(chan struct{}),
+	}
+}
+
+// dataDirRoot returns directory holding all data dir shards.
+func dataDirRoot(location string) string {
+	if strings.HasSuffix(location, "*") {
+		return filepath.Dir(strings.TrimSuffix(location, "*"))
+	}
+	return location
+}
+
+func loadWarmRestartState(path string) (*warmRestartState, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &warmRestartState{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	st := &warmRestartState{}
+	err = json.Unmarshal(b, st)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse warm restart state path=%v", path)
+	}
+	return st, nil
+}
+
+// writeFileAtomic replaces file with data, so a crash never leaves it truncated.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	err := os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *TorrentMap) warmState() *warmRestartState {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	st := &warmRestartState{Torrents: make([]warmTorrent, 0, len(s.entries))}
+	for h, e := range s.entries {
+		wt := warmTorrent{InfoHash: h, Pinned: e.pinned}
+		if e.ttl != s.ttl {
+			wt.TTL = e.ttl.Seconds()
+		}
+		st.Torrents = append(st.Torrents, wt)
+	}
+	sort.Slice(st.Torrents, func(i, j int) bool {
+		return st.Torrents[i].InfoHash < st.Torrents[j].InfoHash
+	})
+	return st
+}
+
+// Save writes active torrents to state file if they changed since last save.
+// State is not saved until restore completes, so torrents still being re-added
+// are not lost.
+func (s *WarmRestart) Save() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if !s.restored {
+		return nil
+	}
+	b, err := json.Marshal(s.tm.warmState())
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(b, s.saved) {
+		return nil
+	}
+	err = writeFileAtomic(s.path, b)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write warm restart state path=%v", s.path)
+	}
+	s.saved = b
+	return nil
+}
+
+func (s *WarmRestart) setRestored() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.restored = true
+}
+
+func (s *WarmRestart) restore(st *warmRestartState) {
+	defer s.setRestored()
+	start := time.Now()
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	for _, wt := range st.Torrents {
+		select {
+		case <-s.closeCh:
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(wt warmTorrent) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			s.restoreTorrent(wt)
+		}(wt)
+	}
+	wg.Wait()
+	log.Infof("warm restart re-added %v torrents in %v", len(st.Torrents), time.Since(start))
+}
+
+func (s *WarmRestart) restoreTorrent(wt warmTorrent) {
+	ctx := context.Background()
+	if wt.TTL > 0 {
+		ctx = WithTorrentTTL(ctx, time.Duration(wt.TTL*float64(time.Second)))
+	}
+	t, err := s.tm.Get(ctx, wt.InfoHash)
+	if err != nil {
+		log.WithError(err).Warnf("failed to re-add torrent on warm restart infohash=%v", wt.InfoHash)
+		return
+	}
+	if t == nil {
+		log.Warnf("no metadata to re-add torrent on warm restart infohash=%v", wt.InfoHash)
+		return
+	}
+	if wt.Pinned {
+		s.tm.Pin(wt.InfoHash, true)
+	}
+}
+
+func (s *WarmRestart) Serve() error {
+	st, err := loadWarmRestartState(s.path)
+	if err != nil {
+		log.WithError(err).Warn("failed to load warm restart state")
+		s.setRestored()
+	} else if len(st.Torrents) > 0 {
+		log.Infof("warm restart re-adding %v torrents concurrency=%v", len(st.Torrents), s.concurrency)
+		go s.restore(st)
+	} else {
+		s.setRestored()
+	}
+	ticker := time.NewTicker(warmRestartSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err = s.Save()
+			if err != nil {
+				log.WithError(err).Warn("failed to save warm restart state")
+			}
+		case <-s.closeCh:
+			return nil
+		}
+	}
+}
+
+func (s *WarmRestart) Close() {
+	close(s.closeCh)
+	err := s.Save()
+	if err != nil {
+		log.WithError(err).Warn("failed to save warm restart state")
+	}
+}
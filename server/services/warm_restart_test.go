@@ -0,0 +1,67 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDataDirRoot(t *testing.T) {
+	if r := dataDirRoot("/data/d*"); r != "/data" {
+		t.Fatalf("expected /data, got %v", r)
+	}
+	if r := dataDirRoot("/data"); r != "/data" {
+		t.Fatalf("expected /data, got %v", r)
+	}
+}
+
+func TestWarmRestart_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), warmRestartStateFile)
+	tm := &TorrentMap{
+		ttl: 10 * time.Minute,
+		entries: map[string]*torrentEntry{
+			"bb": {ttl: 10 * time.Minute, pinned: true},
+			"aa": {ttl: time.Hour},
+		},
+	}
+	wr := &WarmRestart{tm: tm, path: path}
+
+	if err := wr.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no state saved before restore completes, got %v", err)
+	}
+
+	wr.setRestored()
+	if err := wr.Save(); err != nil {
+		t.Fatal(err)
+	}
+	st, err := loadWarmRestartState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []warmTorrent{
+		{InfoHash: "aa", TTL: 3600},
+		{InfoHash: "bb", Pinned: true},
+	}
+	if len(st.Torrents) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, st.Torrents)
+	}
+	for i := range expected {
+		if st.Torrents[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, st.Torrents)
+		}
+	}
+}
+
+func TestLoadWarmRestartState_Missing(t *testing.T) {
+	st, err := loadWarmRestartState(filepath.Join(t.TempDir(), warmRestartStateFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Torrents) != 0 {
+		t.Fatalf("expected empty state, got %v", st.Torrents)
+	}
+}
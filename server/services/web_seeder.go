@@ -3,18 +3,22 @@ package services
 import (
 	"context"
 	"crypto/sha1"
+	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"code.cloudfoundry.org/bytefmt"
 	"github.com/anacrolix/torrent"
 	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/pkg/errors"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
@@ -23,8 +27,17 @@ import (
 var sha1R = regexp.MustCompile("^[0-9a-f]{5,40}$")
 
 const (
-	SourceTorrentPath = "source.torrent"
-	MaxReadaheadFlag  = "max-readahead"
+	SourceTorrentPath    = "source.torrent"
+	MagnetPath           = "magnet"
+	PiecePathPrefix      = "piece/"
+	MaxReadaheadFlag     = "max-readahead"
+	MinReadaheadFlag     = "min-readahead"
+	PieceTimeoutFlag     = "piece-timeout"
+	WebseedPrefixFlag    = "webseed-prefix"
+	PublicURLFlag        = "public-url"
+	FirstByteTimeoutFlag = "first-byte-timeout"
+	StallTimeoutFlag     = "stall-timeout"
+	indexPageSize        = 1000
 )
 
 func RegisterWebSeederFlags(f []cli.Flag) []cli.Flag {
@@ -35,46 +48,125 @@ func RegisterWebSeederFlags(f []cli.Flag) []cli.Flag {
 			Value:  "20MB",
 			EnvVar: "MAX_READAHEAD",
 		},
+		cli.StringFlag{
+			Name:   MinReadaheadFlag,
+			Usage:  "min readahead",
+			Value:  "2MB",
+			EnvVar: "MIN_READAHEAD",
+		},
+		cli.DurationFlag{
+			Name:   PieceTimeoutFlag,
+			Usage:  "max time to wait for a piece requested by index",
+			Value:  60 * time.Second,
+			EnvVar: "PIECE_TIMEOUT",
+		},
+		cli.StringFlag{
+			Name:   WebseedPrefixFlag,
+			Usage:  "url prefix of BEP 19 web seed routes",
+			Value:  "webseed",
+			EnvVar: "WEBSEED_PREFIX",
+		},
+		cli.StringFlag{
+			Name:   PublicURLFlag,
+			Usage:  "public base url of the seeder, added to url-list of served torrents",
+			EnvVar: "PUBLIC_URL",
+		},
+		cli.DurationFlag{
+			Name:   FirstByteTimeoutFlag,
+			Usage:  "max time to wait for the first byte of a file response from torrent, 0 disables",
+			Value:  60 * time.Second,
+			EnvVar: "FIRST_BYTE_TIMEOUT",
+		},
+		cli.DurationFlag{
+			Name:   StallTimeoutFlag,
+			Usage:  "max time to wait for every next chunk of a file response from torrent, 0 disables",
+			Value:  60 * time.Second,
+			EnvVar: "STALL_TIMEOUT",
+		},
 	)
 }
 
+type WebSeederConfig struct {
+	MaxReadahead     int64
+	MinReadahead     int64
+	PieceTimeout     time.Duration
+	WebseedPrefix    string
+	PublicURL        string
+	FirstByteTimeout time.Duration
+	StallTimeout     time.Duration
+}
+
+func NewWebSeederConfig(c *cli.Context) (*WebSeederConfig, error) {
+	maxReadahead, err := bytefmt.ToBytes(c.String(MaxReadaheadFlag))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse max readahead flag")
+	}
+	minReadahead, err := bytefmt.ToBytes(c.String(MinReadaheadFlag))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse min readahead flag")
+	}
+	return &WebSeederConfig{
+		MaxReadahead:     int64(maxReadahead),
+		MinReadahead:     int64(minReadahead),
+		PieceTimeout:     c.Duration(PieceTimeoutFlag),
+		WebseedPrefix:    strings.Trim(c.String(WebseedPrefixFlag), "/"),
+		PublicURL:        strings.TrimSuffix(c.String(PublicURLFlag), "/"),
+		FirstByteTimeout: c.Duration(FirstByteTimeoutFlag),
+		StallTimeout:     c.Duration(StallTimeoutFlag),
+	}, nil
+}
+
 type WebSeeder struct {
-	tm           *TorrentMap
-	st           *StatWeb
-	fcm          *FileCacheMap
-	tfcm         *TorrentFileCountMap
-	tom          *TouchMap
-	v            *Vault
-	cl           *http.Client
-	maxReadahead int64
+	tm    *TorrentMap
+	st    *StatWeb
+	fcm   *FileCacheMap
+	tfcm  *TorrentFileCountMap
+	tom   *TouchMap
+	v     *Vault
+	cl    *http.Client
+	cfg   *WebSeederConfig
+	crcs  *crcCache
+	media *MediaIndex
+	pf    *Prefetcher
 }
 
-func NewWebSeeder(tm *TorrentMap, fcm *FileCacheMap, tfcm *TorrentFileCountMap, tom *TouchMap, st *StatWeb, v *Vault, cl *http.Client, maxReadahead int64) *WebSeeder {
+func NewWebSeeder(tm *TorrentMap, fcm *FileCacheMap, tfcm *TorrentFileCountMap, tom *TouchMap, st *StatWeb, v *Vault, cl *http.Client, cfg *WebSeederConfig, pf *Prefetcher) *WebSeeder {
 	return &WebSeeder{
-		tm:           tm,
-		st:           st,
-		fcm:          fcm,
-		tfcm:         tfcm,
-		tom:          tom,
-		v:            v,
-		cl:           cl,
-		maxReadahead: maxReadahead,
+		tm:    tm,
+		st:    st,
+		fcm:   fcm,
+		tfcm:  tfcm,
+		tom:   tom,
+		v:     v,
+		cl:    cl,
+		cfg:   cfg,
+		crcs:  newCRCCache(crcCacheCapacity, fcm),
+		media: NewMediaIndex(),
+		pf:    pf,
 	}
 }
 
-func (s *WebSeeder) renderTorrent(ctx context.Context, w http.ResponseWriter, h string) {
+func (s *WebSeeder) renderTorrent(w http.ResponseWriter, r *http.Request, h string) {
 	log.Info("serve torrent")
 
-	t, err := s.tm.Get(ctx, h)
+	t, err := s.tm.Get(r.Context(), h)
 
 	if err != nil {
 		log.Error(err)
-		http.Error(w, "failed to get torrent", http.StatusInternalServerError)
+		s.renderTorrentError(w, err)
+		return
+	}
+	if t == nil {
+		http.NotFound(w, r)
 		return
 	}
+	mi := t.Metainfo()
+	if s.cfg.PublicURL != "" {
+		mi.UrlList = append([]string{s.webseedURL(h)}, mi.UrlList...)
+	}
 	w.Header().Set("Content-Type", "application/x-bittorrent")
 
-	err = bencode.NewEncoder(w).Encode(t.Metainfo())
+	err = bencode.NewEncoder(w).Encode(mi)
 	if err != nil {
 		log.WithError(err).Error("failed to encode torrent")
 		http.Error(w, "failed to encode torrent", http.StatusInternalServerError)
@@ -82,6 +174,13 @@ func (s *WebSeeder) renderTorrent(ctx context.Context, w http.ResponseWriter, h
 	}
 }
 
+// webseedURL returns BEP 19 url-list entry pointing to this seeder. Clients
+// append torrent name (and file path for multi-file torrents) to it, which
+// matches File.Path() served by the web seed route.
+func (s *WebSeeder) webseedURL(h string) string {
+	return s.cfg.PublicURL + "/" + s.cfg.WebseedPrefix + "/" + h + "/"
+}
+
 func (s *WebSeeder) addA(path string, w http.ResponseWriter, r *http.Request) {
 	uHref := url.URL{
 		Path:     path,
@@ -105,14 +204,45 @@ func (s *WebSeeder) renderTorrentIndex(w http.ResponseWriter, r *http.Request, h
 
 	if err != nil {
 		log.Error(err)
-		http.Error(w, "failed to get torrent", http.StatusInternalServerError)
+		s.renderTorrentError(w, err)
+		return
+	}
+	if t == nil {
+		http.NotFound(w, r)
+		return
+	}
+	l := NewTorrentListing(h, t)
+	if wantsJSON(r) {
+		s.renderJSON(w, l)
 		return
 	}
-	s.addH(h, w)
+	s.addH(l.Title, w)
 	s.addA("..", w, r)
 	s.addA(SourceTorrentPath, w, r)
-	for _, f := range t.Info().UpvertedFiles() {
-		s.addA(strings.Join(append([]string{t.Info().Name}, f.Path...), "/"), w, r)
+	for _, i := range l.Items {
+		s.addA(i.Path, w, r)
+	}
+}
+
+// renderTorrentError answers request that failed to get torrent.
+func (s *WebSeeder) renderTorrentError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrTooManyTorrents) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(stallRetryAfter.Seconds())))
+		http.Error(w, "too many active torrents", http.StatusTooManyRequests)
+		return
+	}
+	if errors.Is(err, ErrLowDiskSpace) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(stallRetryAfter.Seconds())))
+		http.Error(w, "low disk space", http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, "failed to get torrent", http.StatusInternalServerError)
+}
+
+func (s *WebSeeder) renderJSON(w http.ResponseWriter, v any) {
+	err := writeJSON(w, v)
+	if err != nil {
+		log.WithError(err).Error("failed to encode json")
 	}
 }
 
@@ -195,7 +325,7 @@ func (s *WebSeeder) serveFile(w http.ResponseWriter, r *http.Request, h string,
 
 	// Fallback to torrent
 	logWithField.Info("serve file from torrent")
-	tw, reader, err := s.getTorrentReader(r.Context(), w, h, p)
+	tw, reader, release, err := s.getTorrentReader(r.Context(), w, h, p)
 	if err != nil {
 		if strings.Contains(err.Error(), "PermissionDenied") {
 			logWithField.WithError(err).Warn("permission denied")
@@ -205,10 +335,11 @@ func (s *WebSeeder) serveFile(w http.ResponseWriter, r *http.Request, h string,
 			http.Error(w, "not found", http.StatusNotFound)
 		} else {
 			logWithField.WithError(err).Error("failed to get torrent reader")
-			http.Error(w, "failed to get reader", http.StatusInternalServerError)
+			s.renderTorrentError(w, err)
 		}
 		return
 	}
+	defer release()
 	if reader == nil {
 		logWithField.Info("file not found")
 		http.NotFound(w, r)
@@ -216,9 +347,32 @@ func (s *WebSeeder) serveFile(w http.ResponseWriter, r *http.Request, h string,
 	}
 	defer reader.Close()
 
-	tw.Header().Set("Last-Modified", lastMod.Format(http.TimeFormat))
-	tw.Header().Set("Etag", etag)
-	http.ServeContent(tw, r, p, lastMod, reader)
+	dw := NewHeaderDeferringWriter(tw)
+	dw.Header().Set("Last-Modified", lastMod.Format(http.TimeFormat))
+	dw.Header().Set("Etag", etag)
+	http.ServeContent(dw, r, p, lastMod, reader)
+	if reader.Err() == nil {
+		dw.Commit()
+		return
+	}
+	promStallAborts.WithLabelValues(reader.Stage()).Inc()
+	logWithField.WithError(reader.Err()).Warn("torrent read stalled")
+	if dw.Committed() {
+		// Response is already partially sent, the client only sees a short body.
+		return
+	}
+	s.renderStall(w, reader.Report(requestedRangeEnd(r)))
+}
+
+// renderStall answers request that got no data from the swarm in time.
+func (s *WebSeeder) renderStall(w http.ResponseWriter, rep *StallReport) {
+	for _, k := range []string{"Content-Length", "Content-Range", "Content-Disposition", "Etag", "Last-Modified"} {
+		w.Header().Del(k)
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(rep.RetryAfter))
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(http.StatusServiceUnavailable)
+	s.renderJSON(w, rep)
 }
 
 func (s *WebSeeder) redirectFromVault(w http.ResponseWriter, r *http.Request, h string, p string) (bool, error) {
@@ -284,21 +438,28 @@ func (s *WebSeeder) redirectFromVault(w http.ResponseWriter, r *http.Request, h
 	return false, fmt.Errorf("unexpected vault status %d for %s", resp.StatusCode, fileURL)
 }
 
-func (s *WebSeeder) getTorrentReader(ctx context.Context, w http.ResponseWriter, h string, p string) (http.ResponseWriter, io.ReadSeekCloser, error) {
-	t, err := s.tm.Get(ctx, h)
+// getTorrentReader opens file reader. Returned release func must be called
+// once the reader is not used anymore.
+func (s *WebSeeder) getTorrentReader(ctx context.Context, w http.ResponseWriter, h string, p string) (http.ResponseWriter, *StallReader, func(), error) {
+	t, release, err := s.tm.Open(ctx, h)
 	if err != nil {
-		return w, nil, err
+		return w, nil, release, err
+	}
+	if t == nil {
+		return w, nil, release, nil
 	}
 
 	for _, f := range t.Files() {
 		if f.Path() == p {
 			torReader := f.NewReader()
 			torReader.SetResponsive()
-			torReader.SetReadaheadFunc(NewReadaheadFunc(s.maxReadahead))
-			return NewTouchWriter(w, s.tm, h), torReader, nil
+			s.media.Prioritize(h, t, f)
+			ra := NewAdaptiveReadahead(t, s.cfg.MinReadahead, s.cfg.MaxReadahead)
+			torReader.SetReadaheadFunc(ra.Func())
+			return NewTouchWriter(w, s.tm, h).WithReadahead(ra), NewStallReader(ctx, t, f, torReader, s.cfg.FirstByteTimeout, s.cfg.StallTimeout), release, nil
 		}
 	}
-	return w, nil, nil
+	return w, nil, release, nil
 }
 
 // availableWithoutTorrent checks if the file/directory/root is available via cache or vault,
@@ -385,34 +546,210 @@ func (s *WebSeeder) getHash(r *http.Request) string {
 	return ""
 }
 
+// servePrefetch starts downloading file head and tail, or range given in
+// Range header, and answers immediately. Head and tail size may be set with
+// ?prefetch=<size>.
+func (s *WebSeeder) servePrefetch(w http.ResponseWriter, r *http.Request, h string, p string) {
+	var size int64
+	if v := r.URL.Query().Get("prefetch"); v != "" {
+		b, err := bytefmt.ToBytes(v)
+		if err != nil {
+			http.Error(w, "invalid prefetch size", http.StatusBadRequest)
+			return
+		}
+		size = int64(b)
+	}
+	explicit, err := parsePrefetchRange(r.Header.Get("Range"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := s.pf.Prefetch(r.Context(), h, p, size, explicit)
+	if errors.Is(err, ErrTorrentNotFound) || errors.Is(err, ErrFileNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.WithError(err).Error("failed to prefetch")
+		s.renderTorrentError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(res)
+	if err != nil {
+		log.WithError(err).Error("failed to encode json")
+	}
+}
+
+// servePriority sets download priority of a file given with
+// ?priority=<default|skip|normal|high>.
+func (s *WebSeeder) servePriority(w http.ResponseWriter, r *http.Request, h string, p string) {
+	fp, err := ParseFilePriority(r.URL.Query().Get("priority"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.tm.SetFilePriority(r.Context(), h, p, fp)
+	if errors.Is(err, ErrTorrentNotFound) || errors.Is(err, ErrFileNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.WithError(err).Error("failed to set file priority")
+		s.renderTorrentError(w, err)
+		return
+	}
+	s.renderJSON(w, map[string]string{
+		"info_hash": h,
+		"path":      p,
+		"priority":  string(fp),
+	})
+}
+
+// indexPage parses offset and limit query params of index page.
+func indexPage(r *http.Request) (int, int, error) {
+	offset, limit := 0, indexPageSize
+	var err error
+	q := r.URL.Query()
+	if v := q.Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.Errorf("invalid offset %q", v)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > indexPageSize {
+			return 0, 0, errors.Errorf("invalid limit %q, expected 1-%d", v, indexPageSize)
+		}
+	}
+	return offset, limit, nil
+}
+
 func (s *WebSeeder) renderIndex(w http.ResponseWriter, r *http.Request) {
-	l, err := s.tm.List()
+	offset, limit, err := indexPage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	items, total, err := s.tm.Index(offset, limit)
 	if err != nil {
 		log.Error(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	s.addH("Index", w)
-	for _, v := range l {
-		s.addA(v+"/", w, r)
+	li := NewIndexListing(items, total)
+	if offset+limit < total {
+		q := r.URL.Query()
+		q.Set("offset", strconv.Itoa(offset+limit))
+		q.Set("limit", strconv.Itoa(limit))
+		li.Next = (&url.URL{Path: r.URL.Path, RawQuery: q.Encode()}).String()
+	}
+	if wantsJSON(r) {
+		s.renderJSON(w, li)
+		return
+	}
+	s.addH(li.Title, w)
+	for _, i := range li.Items {
+		s.addA(i.Path, w, r)
+	}
+	if li.Next != "" {
+		_, _ = fmt.Fprintln(w, fmt.Sprintf("<a href=\"%s\">next</a><br />", li.Next))
 	}
 }
 
+func (s *WebSeeder) serveMagnet(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("xt") == "" {
+		http.Error(w, "no xt provided", http.StatusBadRequest)
+		return
+	}
+	format := q.Get("format")
+	q.Del("format")
+	uri := "magnet:?" + q.Encode()
+	_, err := metainfo.ParseMagnetUri(uri)
+	if err != nil {
+		log.WithError(err).Warn("invalid magnet")
+		http.Error(w, "invalid magnet", http.StatusBadRequest)
+		return
+	}
+	t, err := s.tm.AddMagnet(r.Context(), uri)
+	if errors.Is(err, ErrTooManyTorrents) || errors.Is(err, ErrLowDiskSpace) {
+		log.WithError(err).Warn("failed to add magnet")
+		s.renderTorrentError(w, err)
+		return
+	} else if errors.Is(err, ErrMagnetTimeout) {
+		log.WithError(err).Warn("magnet metadata timeout")
+		http.Error(w, "magnet metadata timeout", http.StatusGatewayTimeout)
+		return
+	} else if err != nil {
+		log.WithError(err).Error("failed to add magnet")
+		http.Error(w, "failed to add magnet", http.StatusInternalServerError)
+		return
+	}
+	u := url.URL{
+		Path: "/" + t.InfoHash().HexString() + "/",
+	}
+	if format != "" {
+		u.RawQuery = url.Values{"format": []string{format}}.Encode()
+	}
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// serveWebseed serves BEP 19 (GetRight-style) web seed requests of form
+// /<prefix>/<hash>/<name>[/<path>]. Only exact file paths are served, so
+// directories and unknown files are reported as 404.
+func (s *WebSeeder) serveWebseed(w http.ResponseWriter, r *http.Request, p string) {
+	h, fp, _ := strings.Cut(p, "/")
+	if !sha1R.MatchString(h) || fp == "" || strings.HasSuffix(fp, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.serveFile(w, r, h, fp)
+}
+
 func (s *WebSeeder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.WebseedPrefix != "" {
+		if p, ok := strings.CutPrefix(r.URL.Path, "/"+s.cfg.WebseedPrefix+"/"); ok {
+			s.serveWebseed(w, r, p)
+			return
+		}
+	}
+	if v := r.Header.Get("X-Torrent-TTL"); v != "" {
+		ttl, err := ParseTorrentTTL(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r = r.WithContext(WithTorrentTTL(r.Context(), ttl))
+	}
 	h := s.getHash(r)
-	if h == "" {
+	if h == "" && strings.Trim(r.URL.Path, "/") == MagnetPath {
+		s.serveMagnet(w, r)
+	} else if h == "" {
 		s.renderIndex(w, r)
 	} else {
 		p := r.URL.Path[1:]
 		p = strings.TrimPrefix(p, h+"/")
-		if _, ok := r.URL.Query()["stats"]; ok {
+		if _, ok := r.URL.Query()["prefetch"]; ok && r.Method == http.MethodPost {
+			s.servePrefetch(w, r, h, p)
+		} else if _, ok := r.URL.Query()["priority"]; ok && r.Method == http.MethodPost {
+			s.servePriority(w, r, h, p)
+		} else if _, ok := r.URL.Query()["stats"]; ok {
 			s.serveStats(w, r, h, p)
 		} else if _, ok := r.URL.Query()["done"]; ok {
 			s.serveDone(w, r, h, p)
+		} else if a := r.URL.Query().Get("archive"); a != "" {
+			s.serveArchive(w, r, h, p, a)
 		} else if p == "" {
 			s.renderTorrentIndex(w, r, h)
 		} else if p == SourceTorrentPath {
-			s.renderTorrent(r.Context(), w, h)
+			s.renderTorrent(w, r, h)
+		} else if strings.HasPrefix(p, PiecePathPrefix) {
+			s.servePiece(w, r, h, p)
 		} else {
 			s.serveFile(w, r, h, p)
 		}
@@ -432,8 +769,91 @@ func (s *WebSeeder) serveDone(w http.ResponseWriter, r *http.Request, h string,
 	http.NotFound(w, r)
 }
 
-func NewReadaheadFunc(maxReadahead int64) torrent.ReadaheadFunc {
-	return func(_ torrent.ReadaheadContext) int64 {
-		return maxReadahead
+// servePiece serves verified bytes of a single piece. GET waits until the piece
+// is downloaded, HEAD only reports whether the piece is present locally.
+// Multi-file torrent named "piece" has real files under the same path, such
+// files are served instead of pieces.
+func (s *WebSeeder) servePiece(w http.ResponseWriter, r *http.Request, h string, fp string) {
+	index := strings.TrimPrefix(fp, PiecePathPrefix)
+	logWithField := log.WithFields(log.Fields{
+		"hash":   h,
+		"piece":  index,
+		"method": r.Method,
+		"range":  r.Header.Get("Range"),
+	})
+	t, release, err := s.tm.Open(r.Context(), h)
+	if err != nil {
+		logWithField.WithError(err).Error("failed to get torrent")
+		s.renderTorrentError(w, err)
+		return
+	}
+	defer release()
+	if t != nil && findFile(t, fp) != nil {
+		s.serveFile(w, r, h, fp)
+		return
+	}
+	i, err := strconv.Atoi(index)
+	if err != nil {
+		http.Error(w, "invalid piece index", http.StatusBadRequest)
+		return
+	}
+	if t == nil || i < 0 || i >= t.NumPieces() {
+		http.NotFound(w, r)
+		return
+	}
+	p := t.Piece(i)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if r.Method == http.MethodHead {
+		if !p.State().Complete {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", strconv.FormatInt(p.Info().Length(), 10))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if !p.State().Complete {
+		logWithField.Info("waiting for piece")
+		release := seederPiecePriorities.Hold(t, i)
+		err = waitForPiece(r.Context(), t, i, s.cfg.PieceTimeout)
+		release()
+		if err != nil {
+			logWithField.WithError(err).Warn("piece not available")
+			http.Error(w, "piece not available", http.StatusGatewayTimeout)
+			return
+		}
+	}
+	tr := t.NewReader()
+	tr.SetContext(r.Context())
+	tr.SetReadahead(0)
+	pr := NewPieceReader(tr, p)
+	defer pr.Close()
+	lastMod := time.Unix(0, 0)
+	tw := NewTouchWriter(w, s.tm, h)
+	tw.Header().Set("Last-Modified", lastMod.Format(http.TimeFormat))
+	tw.Header().Set("Etag", fmt.Sprintf("\"%x\"", sha1.Sum([]byte(h+PiecePathPrefix+index))))
+	http.ServeContent(tw, r, index, lastMod, pr)
+}
+
+// waitForPiece waits until piece i is complete, woken by piece state changes
+// of the torrent.
+func waitForPiece(ctx context.Context, t *torrent.Torrent, i int, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	sub := t.SubscribePieceStateChanges()
+	defer sub.Close()
+	for !t.Piece(i).State().Complete {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.Closed():
+			return errors.New("torrent closed")
+		case _, ok := <-sub.Values:
+			if !ok {
+				return errors.New("torrent closed")
+			}
+		}
 	}
+	return nil
 }
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/webtor-io/lazymap"
+)
+
+// newTestWebSeeder serves torrents of given metainfos from input dir without
+// any network activity.
+func newTestWebSeeder(t *testing.T, cfg *WebSeederConfig, mis ...*metainfo.MetaInfo) *WebSeeder {
+	input := t.TempDir()
+	for i, mi := range mis {
+		var b bytes.Buffer
+		if err := mi.Write(&b); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(input, strconv.Itoa(i)+".torrent"), b.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dataDir := t.TempDir()
+	tm := &TorrentMap{
+		tc:         &TorrentClient{cl: newTestClient(t), inited: true},
+		fsm:        &FileStoreMap{p: input, files: map[string]*inputFile{}},
+		entries:    map[string]*torrentEntry{},
+		pending:    map[string]int{},
+		priorities: map[string]map[string]FilePriority{},
+		ttl:        time.Minute,
+	}
+	fcm := &FileCacheMap{p: dataDir, LazyMap: lazymap.New[string](&lazymap.Config{})}
+	tom := &TouchMap{p: dataDir, LazyMap: lazymap.New[bool](&lazymap.Config{})}
+	cfg.FirstByteTimeout = time.Second
+	cfg.StallTimeout = time.Second
+	return NewWebSeeder(tm, fcm, nil, tom, nil, nil, http.DefaultClient, cfg, nil)
+}
+
+func loadSintel(t *testing.T) *metainfo.MetaInfo {
+	mi, err := metainfo.LoadFromFile("../../torrents/Sintel.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mi
+}
+
+// newSingleFileMetaInfo builds single-file torrent of given name and length.
+func newSingleFileMetaInfo(t *testing.T, name string, length int) *metainfo.MetaInfo {
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, bytes.Repeat([]byte{1}, length), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info := metainfo.Info{PieceLength: 16 * 1024}
+	if err := info.BuildFromFilePath(p); err != nil {
+		t.Fatal(err)
+	}
+	b, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &metainfo.MetaInfo{InfoBytes: b}
+}
+
+func TestWebSeeder_ServeWebseed(t *testing.T) {
+	sintel := loadSintel(t)
+	single := newSingleFileMetaInfo(t, "movie.mp4", 100000)
+	s := newTestWebSeeder(t, &WebSeederConfig{WebseedPrefix: "webseed"}, sintel, single)
+	sh := sintel.HashInfoBytes().HexString()
+	ssh := single.HashInfoBytes().HexString()
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		path   string
+		rng    string
+		code   int
+		length string
+	}{
+		{"multi-file", http.MethodHead, sh + "/Sintel/poster.jpg", "", http.StatusOK, "46115"},
+		{"multi-file nested name", http.MethodHead, sh + "/Sintel/Sintel.mp4", "", http.StatusOK, "129241752"},
+		{"single-file", http.MethodHead, ssh + "/movie.mp4", "", http.StatusOK, "100000"},
+		{"directory", http.MethodGet, sh + "/Sintel/", "", http.StatusNotFound, ""},
+		{"directory without slash", http.MethodGet, sh + "/Sintel", "", http.StatusNotFound, ""},
+		{"torrent root", http.MethodGet, sh + "/", "", http.StatusNotFound, ""},
+		{"unknown file", http.MethodGet, sh + "/Sintel/missing.jpg", "", http.StatusNotFound, ""},
+		{"file without torrent name", http.MethodGet, sh + "/poster.jpg", "", http.StatusNotFound, ""},
+		{"invalid hash", http.MethodGet, "nothash/Sintel/poster.jpg", "", http.StatusNotFound, ""},
+		{"post", http.MethodPost, sh + "/Sintel/poster.jpg", "", http.StatusMethodNotAllowed, ""},
+		{"put", http.MethodPut, ssh + "/movie.mp4", "", http.StatusMethodNotAllowed, ""},
+		{"range out of file", http.MethodGet, sh + "/Sintel/poster.jpg", "bytes=50000-60000", http.StatusRequestedRangeNotSatisfiable, ""},
+		{"range out of single file", http.MethodGet, ssh + "/movie.mp4", "bytes=100000-", http.StatusRequestedRangeNotSatisfiable, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "/webseed/"+tc.path, nil)
+			if tc.rng != "" {
+				r.Header.Set("Range", tc.rng)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tc.code {
+				t.Fatalf("expected status %v, got %v: %v", tc.code, w.Code, w.Body.String())
+			}
+			if tc.length != "" && w.Header().Get("Content-Length") != tc.length {
+				t.Fatalf("expected length %v, got %v", tc.length, w.Header().Get("Content-Length"))
+			}
+		})
+	}
+}
+
+func TestWebSeeder_RenderTorrentURLList(t *testing.T) {
+	sintel := loadSintel(t)
+	h := sintel.HashInfoBytes().HexString()
+	for _, tc := range []struct {
+		name      string
+		publicURL string
+		urls      []string
+	}{
+		{"without public url", "", []string{"https://webtorrent.io/torrents/"}},
+		{"with public url", "https://seeder.example.com", []string{"https://seeder.example.com/webseed/" + h + "/", "https://webtorrent.io/torrents/"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestWebSeeder(t, &WebSeederConfig{WebseedPrefix: "webseed", PublicURL: tc.publicURL}, sintel)
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+h+"/"+SourceTorrentPath, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %v", w.Code)
+			}
+			mi, err := metainfo.Load(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(mi.UrlList) != len(tc.urls) {
+				t.Fatalf("expected url-list %v, got %v", tc.urls, mi.UrlList)
+			}
+			for i, u := range tc.urls {
+				if mi.UrlList[i] != u {
+					t.Fatalf("expected url-list %v, got %v", tc.urls, mi.UrlList)
+				}
+			}
+			if mi.HashInfoBytes().HexString() != h {
+				t.Fatal("expected info unchanged")
+			}
+		})
+	}
+}
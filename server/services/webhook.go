@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	WebhookURLFlag        = "webhook-url"
+	WebhookSecretFlag     = "webhook-secret"
+	WebhookEventsFlag     = "webhook-events"
+	WebhookMaxRetriesFlag = "webhook-max-retries"
+	WebhookTimeoutFlag    = "webhook-timeout"
+	webhookQueueSize      = 1000
+	webhookMinBackoff     = time.Second
+	webhookMaxBackoff     = time.Minute
+
+	EventFileCompleted    = "file.completed"
+	EventTorrentCompleted = "torrent.completed"
+	EventPieceEvicted     = "piece.evicted"
+	EventTorrentDropped   = "torrent.dropped"
+)
+
+var webhookEvents = []string{EventFileCompleted, EventTorrentCompleted, EventPieceEvicted, EventTorrentDropped}
+
+var promWebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "torrent_web_seeder_webhook_deliveries_total",
+	Help: "Total number of webhook deliveries by event and result (success/failed/dropped)",
+}, []string{"event", "result"})
+
+func init() {
+	prometheus.MustRegister(promWebhookDeliveries)
+}
+
+func RegisterWebhookFlags(f []cli.Flag) []cli.Flag {
+	return append(f,
+		cli.StringSliceFlag{
+			Name:   WebhookURLFlag,
+			Usage:  "url receiving webhook events, may be repeated",
+			EnvVar: "WEBHOOK_URL",
+		},
+		cli.StringFlag{
+			Name:   WebhookSecretFlag,
+			Usage:  "secret used to sign webhook payloads with hmac-sha256",
+			EnvVar: "WEBHOOK_SECRET",
+		},
+		cli.StringSliceFlag{
+			Name:   WebhookEventsFlag,
+			Usage:  "webhook events to send, all events if empty",
+			EnvVar: "WEBHOOK_EVENTS",
+		},
+		cli.IntFlag{
+			Name:   WebhookMaxRetriesFlag,
+			Usage:  "max retries of failed webhook delivery",
+			Value:  5,
+			EnvVar: "WEBHOOK_MAX_RETRIES",
+		},
+		cli.DurationFlag{
+			Name:   WebhookTimeoutFlag,
+			Usage:  "timeout of single webhook delivery attempt",
+			Value:  10 * time.Second,
+			EnvVar: "WEBHOOK_TIMEOUT",
+		},
+	)
+}
+
+// WebhookEvent is a JSON payload posted to webhook targets.
+type WebhookEvent struct {
+	ID       string    `json:"id"`
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	InfoHash string    `json:"info_hash"`
+	Path     string    `json:"path,omitempty"`
+	Pieces   []int     `json:"pieces,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+type webhookDelivery struct {
+	event string
+	id    string
+	body  []byte
+}
+
+type webhookTarget struct {
+	url string
+	ch  chan webhookDelivery
+}
+
+// Webhooks posts events to configured targets. Every target has its own
+// queue, so slow target does not delay others. Failed deliveries are retried
+// with exponential backoff. Payload is signed with X-Webhook-Signature header
+// when secret is set.
+type Webhooks struct {
+	targets    []*webhookTarget
+	events     map[string]bool
+	secret     string
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	cl         *http.Client
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// NewWebhooks returns nil if no webhook url is configured.
+func NewWebhooks(c *cli.Context) (*Webhooks, error) {
+	if len(c.StringSlice(WebhookURLFlag)) == 0 {
+		return nil, nil
+	}
+	return newWebhooks(c.StringSlice(WebhookURLFlag), c.String(WebhookSecretFlag), c.StringSlice(WebhookEventsFlag),
+		c.Int(WebhookMaxRetriesFlag), c.Duration(WebhookTimeoutFlag))
+}
+
+func newWebhooks(urls []string, secret string, events []string, retries int, timeout time.Duration) (*Webhooks, error) {
+	var filter map[string]bool
+	if len(events) > 0 {
+		filter = map[string]bool{}
+		for _, e := range events {
+			known := false
+			for _, we := range webhookEvents {
+				known = known || e == we
+			}
+			if !known {
+				return nil, errors.Errorf("unknown webhook event %q", e)
+			}
+			filter[e] = true
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Webhooks{
+		events:     filter,
+		secret:     secret,
+		retries:    max(retries, 0),
+		minBackoff: webhookMinBackoff,
+		maxBackoff: webhookMaxBackoff,
+		cl:         &http.Client{Timeout: timeout},
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	for _, u := range urls {
+		s.targets = append(s.targets, &webhookTarget{
+			url: u,
+			ch:  make(chan webhookDelivery, webhookQueueSize),
+		})
+	}
+	return s, nil
+}
+
+// Emit queues event for delivery to all targets. It never blocks, events are
+// dropped when target queue is full. Emit on nil Webhooks does nothing.
+func (s *Webhooks) Emit(e WebhookEvent) {
+	if s == nil || (s.events != nil && !s.events[e.Type]) {
+		return
+	}
+	e.ID = randomID()
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.WithError(err).Errorf("failed to encode webhook event type=%v", e.Type)
+		return
+	}
+	d := webhookDelivery{event: e.Type, id: e.ID, body: body}
+	for _, t := range s.targets {
+		select {
+		case t.ch <- d:
+		default:
+			promWebhookDeliveries.WithLabelValues(e.Type, "dropped").Inc()
+			log.Warnf("webhook queue is full, event dropped type=%v url=%v", e.Type, t.url)
+		}
+	}
+}
+
+// sign returns hex encoded hmac-sha256 of body.
+func (s *Webhooks) sign(body []byte) string {
+	m := hmac.New(sha256.New, []byte(s.secret))
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+type webhookStatusError struct {
+	code int
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook responded with status %d", e.code)
+}
+
+// retryable reports whether failed delivery may succeed later: network errors,
+// timeouts, throttling and server errors.
+func (e *webhookStatusError) retryable() bool {
+	return e.code >= 500 || e.code == http.StatusRequestTimeout || e.code == http.StatusTooManyRequests
+}
+
+func (s *Webhooks) post(url string, d webhookDelivery) error {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, url, bytes.NewReader(d.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", jsonContentType)
+	req.Header.Set("X-Webhook-Event", d.event)
+	req.Header.Set("X-Webhook-Id", d.id)
+	if s.secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+s.sign(d.body))
+	}
+	res, err := s.cl.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &webhookStatusError{code: res.StatusCode}
+	}
+	return nil
+}
+
+func (s *Webhooks) deliver(url string, d webhookDelivery) {
+	backoff := s.minBackoff
+	for attempt := 0; ; attempt++ {
+		err := s.post(url, d)
+		if err == nil {
+			promWebhookDeliveries.WithLabelValues(d.event, "success").Inc()
+			return
+		}
+		var se *webhookStatusError
+		if s.ctx.Err() != nil {
+			return
+		}
+		if attempt >= s.retries || (errors.As(err, &se) && !se.retryable()) {
+			promWebhookDeliveries.WithLabelValues(d.event, "failed").Inc()
+			log.WithError(err).Warnf("failed to deliver webhook type=%v id=%v url=%v attempts=%v", d.event, d.id, url, attempt+1)
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-s.ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, s.maxBackoff)
+	}
+}
+
+func (s *Webhooks) run(t *webhookTarget) {
+	defer s.wg.Done()
+	for {
+		select {
+		case d := <-t.ch:
+			s.deliver(t.url, d)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Webhooks) Serve() error {
+	log.Infof("serving Webhooks targets=%v", len(s.targets))
+	for _, t := range s.targets {
+		s.wg.Add(1)
+		go s.run(t)
+	}
+	<-s.ctx.Done()
+	return nil
+}
+
+func (s *Webhooks) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func newTestWebhooks(t *testing.T, url string, events []string) *Webhooks {
+	wh, err := newWebhooks([]string{url}, "secret", events, 3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wh.minBackoff = time.Millisecond
+	wh.maxBackoff = time.Millisecond
+	go func() {
+		_ = wh.Serve()
+	}()
+	t.Cleanup(wh.Close)
+	return wh
+}
+
+func TestWebhooks_SignedDeliveryWithRetry(t *testing.T) {
+	var attempts atomic.Int32
+	received := make(chan WebhookEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		m := hmac.New(sha256.New, []byte("secret"))
+		m.Write(body)
+		if r.Header.Get("X-Webhook-Signature") != "sha256="+hex.EncodeToString(m.Sum(nil)) {
+			t.Errorf("invalid signature %v", r.Header.Get("X-Webhook-Signature"))
+		}
+		if r.Header.Get("X-Webhook-Event") != EventFileCompleted {
+			t.Errorf("unexpected event header %v", r.Header.Get("X-Webhook-Event"))
+		}
+		var e WebhookEvent
+		if err := json.Unmarshal(body, &e); err != nil {
+			t.Error(err)
+		}
+		received <- e
+	}))
+	defer srv.Close()
+
+	wh := newTestWebhooks(t, srv.URL, nil)
+	wh.Emit(WebhookEvent{Type: EventFileCompleted, InfoHash: "abc", Path: "t/a.mkv"})
+
+	select {
+	case e := <-received:
+		if e.InfoHash != "abc" || e.Path != "t/a.mkv" || e.ID == "" {
+			t.Fatalf("unexpected event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Fatalf("expected 3 attempts, got %v", n)
+	}
+}
+
+func TestWebhooks_NoRetryOnClientError(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	wh := newTestWebhooks(t, srv.URL, nil)
+	wh.Emit(WebhookEvent{Type: EventTorrentDropped, InfoHash: "abc", Reason: dropReasonIdle})
+	time.Sleep(100 * time.Millisecond)
+	if n := attempts.Load(); n != 1 {
+		t.Fatalf("expected single attempt, got %v", n)
+	}
+}
+
+func TestWebhooks_EventFilter(t *testing.T) {
+	if _, err := newWebhooks([]string{"http://localhost"}, "", []string{"file.unknown"}, 0, time.Second); err == nil {
+		t.Fatal("expected unknown event error")
+	}
+	received := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-Webhook-Event")
+	}))
+	defer srv.Close()
+
+	wh := newTestWebhooks(t, srv.URL, []string{EventTorrentCompleted})
+	wh.Emit(WebhookEvent{Type: EventPieceEvicted, InfoHash: "abc"})
+	wh.Emit(WebhookEvent{Type: EventTorrentCompleted, InfoHash: "abc"})
+	select {
+	case e := <-received:
+		if e != EventTorrentCompleted {
+			t.Fatalf("expected only %v delivered, got %v", EventTorrentCompleted, e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	var nilWebhooks *Webhooks
+	nilWebhooks.Emit(WebhookEvent{Type: EventTorrentCompleted})
+}
+
+func TestTorrentStorage_BatchesEvictedPieces(t *testing.T) {
+	received := make(chan WebhookEvent, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e WebhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		received <- e
+	}))
+	defer srv.Close()
+
+	ts := &torrentStorage{wh: newTestWebhooks(t, srv.URL, nil)}
+	for _, idx := range []int{7, 3, 5} {
+		ts.emitEvicted(idx)
+	}
+	ts.flushEvicted()
+	select {
+	case e := <-received:
+		if e.Type != EventPieceEvicted || len(e.Pieces) != 3 || e.Pieces[0] != 3 || e.Pieces[2] != 7 {
+			t.Fatalf("unexpected event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	ts.flushEvicted()
+	select {
+	case e := <-received:
+		t.Fatalf("unexpected event %+v", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPieceCompletion_NotifiesAgainAfterEviction(t *testing.T) {
+	received := make(chan WebhookEvent, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e WebhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		received <- e
+	}))
+	defer srv.Close()
+	wh := newTestWebhooks(t, srv.URL, nil)
+	expect := func(types ...string) {
+		t.Helper()
+		got := map[string]bool{}
+		for range types {
+			select {
+			case e := <-received:
+				got[e.Type] = true
+			case <-time.After(5 * time.Second):
+				t.Fatalf("expected events %v, got %v", types, got)
+			}
+		}
+		for _, typ := range types {
+			if !got[typ] {
+				t.Fatalf("expected events %v, got %v", types, got)
+			}
+		}
+		select {
+		case e := <-received:
+			t.Fatalf("unexpected event %+v", e)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	info := &metainfo.Info{PieceLength: 16, Length: 16, Name: "a", Pieces: makeDummyPieces(1)}
+	pc, err := NewPieceCompletion(t.TempDir(), info, metainfo.Hash{1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	pk := metainfo.PieceKey{InfoHash: metainfo.Hash{1}, Index: 0}
+
+	if err := pc.Set(pk, true); err != nil {
+		t.Fatal(err)
+	}
+	completed, err := pc.notify(wh, false)
+	if err != nil || !completed {
+		t.Fatalf("expected torrent completed, got %v %v", completed, err)
+	}
+	expect(EventFileCompleted, EventTorrentCompleted)
+
+	// Eviction uncompletes piece and its files.
+	if err := pc.Set(pk, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.UncompleteFiles([]string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if completed, err = pc.notify(wh, completed); err != nil || completed {
+		t.Fatalf("expected torrent not completed, got %v %v", completed, err)
+	}
+	expect()
+
+	if err := pc.Set(pk, true); err != nil {
+		t.Fatal(err)
+	}
+	if completed, err = pc.notify(wh, completed); err != nil || !completed {
+		t.Fatalf("expected torrent completed again, got %v %v", completed, err)
+	}
+	expect(EventFileCompleted, EventTorrentCompleted)
+}